@@ -0,0 +1,332 @@
+package gozk
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// GetTimeZone returns the access time zone by the given ID
+func (zk *ZK) GetTimeZone(id int) (*AccessTimeZone, error) {
+	data, err := zk.readAccessData(CMD_TZ_RRQ, id, 28)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeTimeZone(id, data), nil
+}
+
+// SetTimeZone writes the access time zone, days missing from Windows are closed all day
+func (zk *ZK) SetTimeZone(tz *AccessTimeZone) error {
+	return zk.writeAccessData(CMD_TZ_WRQ, tz.ID, encodeTimeZone(tz))
+}
+
+// GetUserTimeZone returns the time zones assigned to the user by the given UID
+func (zk *ZK) GetUserTimeZone(uid int) (*UserTimeZone, error) {
+	data, err := zk.readAccessData(CMD_USERTZ_RRQ, uid, 8)
+	if err != nil {
+		return nil, err
+	}
+
+	v := mustUnpack([]string{"H", "H", "H", "H"}, data)
+	return &UserTimeZone{
+		UID:       uid,
+		UseGroup:  v[0].(int) == 0,
+		TimeZones: [3]int{v[1].(int), v[2].(int), v[3].(int)},
+	}, nil
+}
+
+// SetUserTimeZone assigns the time zones to the user
+func (zk *ZK) SetUserTimeZone(tz *UserTimeZone) error {
+	ownTimeZones := 1
+	if tz.UseGroup {
+		ownTimeZones = 0
+	}
+
+	data := mustPack([]string{"H", "H", "H", "H"}, []interface{}{ownTimeZones, tz.TimeZones[0], tz.TimeZones[1], tz.TimeZones[2]})
+	return zk.writeAccessData(CMD_USERTZ_WRQ, tz.UID, data)
+}
+
+// GetGroupTimeZone returns the time zones assigned to the group
+func (zk *ZK) GetGroupTimeZone(group int) (*GroupTimeZone, error) {
+	data, err := zk.readAccessData(CMD_GRPTZ_RRQ, group, 6)
+	if err != nil {
+		return nil, err
+	}
+
+	v := mustUnpack([]string{"H", "H", "H"}, data)
+	return &GroupTimeZone{
+		Group:     group,
+		TimeZones: [3]int{v[0].(int), v[1].(int), v[2].(int)},
+	}, nil
+}
+
+// SetGroupTimeZone assigns the time zones to the group
+func (zk *ZK) SetGroupTimeZone(tz *GroupTimeZone) error {
+	data := mustPack([]string{"H", "H", "H"}, []interface{}{tz.TimeZones[0], tz.TimeZones[1], tz.TimeZones[2]})
+	return zk.writeAccessData(CMD_GRPTZ_WRQ, tz.Group, data)
+}
+
+// GetUserGroup returns the group of the user by the given UID
+func (zk *ZK) GetUserGroup(uid int) (int, error) {
+	data, err := zk.readAccessData(CMD_USERGRP_RRQ, uid, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(data[0]), nil
+}
+
+// SetUserGroup moves the user by the given UID to the group
+func (zk *ZK) SetUserGroup(uid, group int) error {
+	return zk.writeAccessData(CMD_USERGRP_WRQ, uid, []byte{byte(group)})
+}
+
+// GetUnlockCombinations returns the multi-person unlock rules of the device
+func (zk *ZK) GetUnlockCombinations() ([]*UnlockCombination, error) {
+	res, err := zk.sendCommand(CMD_ULG_RRQ, nil, 1024)
+	if err != nil {
+		return nil, err
+	}
+
+	if !res.Status {
+		return nil, errors.New("can not read unlock combinations")
+	}
+
+	if len(res.Data) < maxUnlockCombinations*unlockCombinationSize {
+		return nil, fmt.Errorf("unlock combinations are %d bytes long", len(res.Data))
+	}
+
+	return decodeUnlockCombinations(res.Data), nil
+}
+
+// SetUnlockCombinations replaces the multi-person unlock rules of the device
+func (zk *ZK) SetUnlockCombinations(combinations []*UnlockCombination) error {
+	data, err := encodeUnlockCombinations(combinations)
+	if err != nil {
+		return err
+	}
+
+	res, err := zk.sendCommand(CMD_ULG_WRQ, data, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can not write unlock combinations")
+	}
+
+	return zk.refreshData()
+}
+
+// ResetAccessControl restores the access control configuration of the device to its defaults
+func (zk *ZK) ResetAccessControl() error {
+	res, err := zk.sendCommand(CMD_CLEAR_ACC, nil, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can not reset access control")
+	}
+
+	return zk.refreshData()
+}
+
+// AccessControlSnapshot reads the whole access control configuration of the device.
+// Every time zone and group time zone slot is kept, unused ones included, so that applying it back
+// doesn't leave the device defaults in place.
+func (zk *ZK) AccessControlSnapshot() (*AccessControl, error) {
+	snapshot := &AccessControl{
+		TimeZones:      []*AccessTimeZone{},
+		GroupTimeZones: []*GroupTimeZone{},
+		UserTimeZones:  []*UserTimeZone{},
+		UserGroups:     map[int]int{},
+	}
+
+	for id := 1; id <= maxTimeZones; id++ {
+		tz, err := zk.GetTimeZone(id)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.TimeZones = append(snapshot.TimeZones, tz)
+	}
+
+	for group := 1; group <= maxGroups; group++ {
+		tz, err := zk.GetGroupTimeZone(group)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.GroupTimeZones = append(snapshot.GroupTimeZones, tz)
+	}
+
+	users, err := zk.GetUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		tz, err := zk.GetUserTimeZone(user.UID)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.UserTimeZones = append(snapshot.UserTimeZones, tz)
+
+		group, err := zk.GetUserGroup(user.UID)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.UserGroups[user.UID] = group
+	}
+
+	if snapshot.UnlockCombinations, err = zk.GetUnlockCombinations(); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// ApplyAccessControl resets the access control configuration of the device then writes the snapshot back
+func (zk *ZK) ApplyAccessControl(snapshot *AccessControl) error {
+	if err := zk.ResetAccessControl(); err != nil {
+		return err
+	}
+
+	for _, tz := range snapshot.TimeZones {
+		if err := zk.SetTimeZone(tz); err != nil {
+			return err
+		}
+	}
+
+	for _, tz := range snapshot.GroupTimeZones {
+		if err := zk.SetGroupTimeZone(tz); err != nil {
+			return err
+		}
+	}
+
+	for uid, group := range snapshot.UserGroups {
+		if err := zk.SetUserGroup(uid, group); err != nil {
+			return err
+		}
+	}
+
+	for _, tz := range snapshot.UserTimeZones {
+		if err := zk.SetUserTimeZone(tz); err != nil {
+			return err
+		}
+	}
+
+	return zk.SetUnlockCombinations(snapshot.UnlockCombinations)
+}
+
+// readAccessData reads an access control record by its ID, the record must be at least size bytes long
+func (zk *ZK) readAccessData(command, id, size int) ([]byte, error) {
+	commandString := mustPack([]string{"I"}, []interface{}{id})
+	res, err := zk.sendCommand(command, commandString, 1024)
+	if err != nil {
+		return nil, err
+	}
+
+	if !res.Status {
+		return nil, fmt.Errorf("can not read record %d with command %d", id, command)
+	}
+
+	if len(res.Data) < size {
+		return nil, fmt.Errorf("record %d is %d bytes long, expected %d", id, len(res.Data), size)
+	}
+
+	return res.Data[:size], nil
+}
+
+// writeAccessData writes an access control record by its ID
+func (zk *ZK) writeAccessData(command, id int, data []byte) error {
+	commandString := mustPack([]string{"I"}, []interface{}{id})
+	commandString = append(commandString, data...)
+
+	res, err := zk.sendCommand(command, commandString, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return fmt.Errorf("can not write record %d with command %d", id, command)
+	}
+
+	return zk.refreshData()
+}
+
+// decodeTimeZone decodes a time zone record made of 7 days, from Sunday,
+// of start hour, start minute, end hour and end minute.
+func decodeTimeZone(id int, data []byte) *AccessTimeZone {
+	tz := &AccessTimeZone{ID: id, Windows: map[time.Weekday]TimeWindow{}}
+
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		v := data[int(day)*4:]
+		tz.Windows[day] = TimeWindow{
+			StartHour:   int(v[0]),
+			StartMinute: int(v[1]),
+			EndHour:     int(v[2]),
+			EndMinute:   int(v[3]),
+		}
+	}
+
+	return tz
+}
+
+const (
+	maxTimeZones          = 50
+	maxGroups             = 5
+	maxUnlockCombinations = 10
+	unlockCombinationSize = 5
+)
+
+// decodeUnlockCombinations decodes the 10 combinations of 5 group IDs, unused slots are 0
+func decodeUnlockCombinations(data []byte) []*UnlockCombination {
+	combinations := []*UnlockCombination{}
+
+	for i := 0; i < maxUnlockCombinations; i++ {
+		combination := &UnlockCombination{ID: i + 1, Groups: []int{}}
+		for _, group := range data[i*unlockCombinationSize : (i+1)*unlockCombinationSize] {
+			if group != 0 {
+				combination.Groups = append(combination.Groups, int(group))
+			}
+		}
+
+		if len(combination.Groups) > 0 {
+			combinations = append(combinations, combination)
+		}
+	}
+
+	return combinations
+}
+
+func encodeUnlockCombinations(combinations []*UnlockCombination) ([]byte, error) {
+	data := make([]byte, maxUnlockCombinations*unlockCombinationSize)
+
+	for _, combination := range combinations {
+		if combination.ID < 1 || combination.ID > maxUnlockCombinations {
+			return nil, fmt.Errorf("invalid unlock combination id %d", combination.ID)
+		}
+
+		if len(combination.Groups) > unlockCombinationSize {
+			return nil, fmt.Errorf("unlock combination %d has more than %d groups", combination.ID, unlockCombinationSize)
+		}
+
+		for i, group := range combination.Groups {
+			data[(combination.ID-1)*unlockCombinationSize+i] = byte(group)
+		}
+	}
+
+	return data, nil
+}
+
+func encodeTimeZone(tz *AccessTimeZone) []byte {
+	data := []byte{}
+
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		w := tz.Windows[day]
+		data = append(data, byte(w.StartHour), byte(w.StartMinute), byte(w.EndHour), byte(w.EndMinute))
+	}
+
+	return data
+}
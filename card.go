@@ -0,0 +1,141 @@
+package gozk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoCard          = errors.New("no card presented")
+	ErrCardWriteFailed = errors.New("failed to write the card")
+	ErrCardTimeout     = errors.New("timed out waiting for a card")
+)
+
+// FindUserByCard returns the user the card is assigned to
+func (zk *ZK) FindUserByCard(card uint32) (*User, error) {
+	if card == 0 {
+		return nil, errors.New("card 0 means no card")
+	}
+
+	users, err := zk.GetUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		if user.Card == card {
+			return user, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no user with card %d", card)
+}
+
+// AssignCard assigns the card to the user by the given UID, 0 removes the card of the user
+func (zk *ZK) AssignCard(uid int, card uint32) error {
+	users, err := zk.GetUsers()
+	if err != nil {
+		return err
+	}
+
+	var user *User
+	for _, u := range users {
+		if u.UID == uid {
+			user = u
+		} else if card != 0 && u.Card == card {
+			return fmt.Errorf("card %d is already assigned to user %d", card, u.UID)
+		}
+	}
+
+	if user == nil {
+		return fmt.Errorf("user %d not found", uid)
+	}
+
+	user.Card = card
+	return zk.SetUser(user)
+}
+
+// WriteMifareCard stores the user and its fingerprint templates on a Mifare card.
+// The card has to be presented to the device within CardTimeout.
+func (zk *ZK) WriteMifareCard(user *User, fingers []*Finger) error {
+	packetSize, err := zk.detectUserPacketSize()
+	if err != nil {
+		return err
+	}
+
+	commandString, err := zk.encodeUser(user, packetSize)
+	if err != nil {
+		return err
+	}
+
+	for _, finger := range fingers {
+		commandString = append(commandString, mustPack([]string{"H", "H", "b", "b"}, []interface{}{len(finger.Template) + 6, user.UID, finger.FingerID, finger.Valid})...)
+		commandString = append(commandString, finger.Template...)
+	}
+
+	return zk.writeCard(CMD_WRITE_MIFARE, commandString)
+}
+
+// EmptyMifareCard erases the data stored on a Mifare card.
+// The card has to be presented to the device within CardTimeout.
+func (zk *ZK) EmptyMifareCard() error {
+	return zk.writeCard(CMD_EMPTY_MIFARE, nil)
+}
+
+// writeCard sends the card command then waits for the device to report the result
+// once a card has been presented.
+func (zk *ZK) writeCard(command int, commandString []byte) error {
+	if err := zk.regEvent(EF_HIDNUM | EF_VERIFY); err != nil {
+		return err
+	}
+	defer zk.regEvent(0)
+
+	res, err := zk.sendCommand(command, commandString, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return ErrCardWriteFailed
+	}
+
+	deadline := time.Now().Add(CardTimeout)
+	for time.Now().Before(deadline) {
+		code, flag, data, err := zk.receivePacket(time.Until(deadline))
+		if err != nil {
+			if strings.Contains(err.Error(), "timeout") {
+				return ErrCardTimeout
+			}
+			return err
+		}
+
+		switch code {
+		case CMD_ACK_OK:
+			return nil
+		case CMD_ACK_ERROR, CMD_ACK_ERROR_DATA:
+			return ErrCardWriteFailed
+		case CMD_REG_EVENT:
+			if err := zk.ackOK(); err != nil {
+				return err
+			}
+
+			// The card being swiped or verified is reported before the result
+			if flag == EF_HIDNUM || flag == EF_VERIFY || len(data) < 2 {
+				continue
+			}
+
+			switch result := mustUnpack([]string{"H"}, data[:2])[0].(int); result {
+			case 0:
+				return nil
+			case 1:
+				return ErrNoCard
+			default:
+				return fmt.Errorf("%w: code %d", ErrCardWriteFailed, result)
+			}
+		}
+	}
+
+	return ErrCardTimeout
+}
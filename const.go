@@ -50,6 +50,8 @@ const (
 	CMD_WRITE_MIFARE    = 76 // Write the Mifare card
 	CMD_EMPTY_MIFARE    = 78 // Clear the Mifare card
 
+	CMD_SAVE_USERTEMPS = 110 // Save the users and fingerprint templates uploaded to the data buffer
+
 	CMD_GET_TIME  = 201 // Obtain the machine time
 	CMD_SET_TIME  = 202 // Set machines time
 	CMD_REG_EVENT = 500 // Register the event
@@ -95,6 +97,7 @@ const (
 	EF_ENROLLFINGER = (1 << 3) // be real-time to enroll fingerprint
 	EF_BUTTON       = (1 << 4) // be real-time to press button
 	EF_UNLOCK       = (1 << 5) // be real-time to unlock
+	EF_HIDNUM       = (1 << 6) // be real-time to return the number of a swiped card
 	EF_VERIFY       = (1 << 7) // be real-time to verify fingerprint
 	EF_FPFTR        = (1 << 8) // be real-time capture fingerprint minutia
 	EF_ALARM        = (1 << 9) // Alarm signal
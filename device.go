@@ -0,0 +1,237 @@
+package gozk
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetOption reads a configuration parameter of the device, like ~SerialNumber or IPAddress
+func (zk *ZK) GetOption(key string) (string, error) {
+	res, err := zk.sendCommand(CMD_OPTIONS_RRQ, []byte(key+"\x00"), 1024)
+	if err != nil {
+		return "", err
+	}
+
+	if !res.Status {
+		return "", fmt.Errorf("can not get option %s", key)
+	}
+
+	_, value := parseOption(res.Data)
+	return value, nil
+}
+
+// SetOption writes a configuration parameter of the device and makes the device reload its configuration
+func (zk *ZK) SetOption(key, value string) error {
+	if err := zk.writeOption(key, value); err != nil {
+		return err
+	}
+
+	return zk.refreshOption()
+}
+
+// GetSerialNumber returns the serial number of the device
+func (zk *ZK) GetSerialNumber() (string, error) {
+	return zk.GetOption("~SerialNumber")
+}
+
+// GetPlatform returns the hardware platform of the device
+func (zk *ZK) GetPlatform() (string, error) {
+	return zk.GetOption("~Platform")
+}
+
+// GetDeviceName returns the model name of the device
+func (zk *ZK) GetDeviceName() (string, error) {
+	return zk.GetOption("~DeviceName")
+}
+
+// GetMAC returns the MAC address of the device
+func (zk *ZK) GetMAC() (string, error) {
+	return zk.GetOption("MAC")
+}
+
+// GetIPAddress returns the IP address configured on the device
+func (zk *ZK) GetIPAddress() (string, error) {
+	return zk.GetOption("IPAddress")
+}
+
+// GetFingerprintAlgorithm returns the version of the fingerprint algorithm, 9 or 10 most of the time
+func (zk *ZK) GetFingerprintAlgorithm() (string, error) {
+	return zk.GetOption("~ZKFPVersion")
+}
+
+// GetFaceAlgorithm returns the version of the face algorithm, empty if the device has no face reader
+func (zk *ZK) GetFaceAlgorithm() (string, error) {
+	return zk.GetOption("ZKFaceVersion")
+}
+
+// GetDeviceState returns what the device is currently doing
+func (zk *ZK) GetDeviceState() (*DeviceStatus, error) {
+	state, err := zk.readState(CMD_STATE_RRQ)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeviceStatus{DeviceID: zk.deviceID, State: DeviceState(state)}, nil
+}
+
+// GetDoorState returns the state of the door sensor wired to the device
+func (zk *ZK) GetDoorState() (*DoorStatus, error) {
+	state, err := zk.readState(CMD_DOORSTATE_RRQ)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DoorStatus{DeviceID: zk.deviceID, State: DoorState(state)}, nil
+}
+
+func (zk *ZK) readState(command int) (int, error) {
+	res, err := zk.sendCommand(command, nil, 1024)
+	if err != nil {
+		return 0, err
+	}
+
+	if !res.Status {
+		return 0, fmt.Errorf("can not read state with command %d", command)
+	}
+
+	switch {
+	case len(res.Data) >= 4:
+		return mustUnpack([]string{"I"}, res.Data[:4])[0].(int), nil
+	case len(res.Data) >= 1:
+		return int(res.Data[0]), nil
+	default:
+		return 0, errors.New("empty state response")
+	}
+}
+
+// SetNetworkConfig changes the IP address, netmask and gateway of the device then restarts it.
+// When reconnect is true it waits for the device to come back at the new address and connects to it.
+func (zk *ZK) SetNetworkConfig(ip, netmask, gateway string, reconnect bool) error {
+	for _, addr := range []string{ip, netmask, gateway} {
+		if net.ParseIP(addr).To4() == nil {
+			return fmt.Errorf("invalid IPv4 address %q", addr)
+		}
+	}
+
+	if err := zk.setOptions([][2]string{{"IPAddress", ip}, {"NetMask", netmask}, {"GATEIPAddress", gateway}}); err != nil {
+		return err
+	}
+
+	zk.host = ip
+	return zk.restartAndReconnect(reconnect)
+}
+
+// SetCommPort changes the port the device listens on then restarts it.
+// When reconnect is true it waits for the device to come back and connects to the new port.
+func (zk *ZK) SetCommPort(port int, reconnect bool) error {
+	key := "UDPPort"
+	if zk.tcp {
+		key = "TCPPort"
+	}
+
+	if err := zk.setOptions([][2]string{{key, strconv.Itoa(port)}}); err != nil {
+		return err
+	}
+
+	zk.port = port
+	return zk.restartAndReconnect(reconnect)
+}
+
+// SetCommKey changes the communication key (PIN) of the device then restarts it.
+// When reconnect is true it waits for the device to come back and connects with the new key.
+func (zk *ZK) SetCommKey(key int, reconnect bool) error {
+	if err := zk.setOptions([][2]string{{"COMKey", strconv.Itoa(key)}}); err != nil {
+		return err
+	}
+
+	zk.pin = key
+	return zk.restartAndReconnect(reconnect)
+}
+
+// setOptions writes the options then reads them back to confirm the device accepted them.
+// The configuration isn't reloaded, so a half written network setup can't drop the connection,
+// the restart that follows applies them all at once.
+func (zk *ZK) setOptions(options [][2]string) error {
+	for _, option := range options {
+		if err := zk.writeOption(option[0], option[1]); err != nil {
+			return err
+		}
+	}
+
+	for _, option := range options {
+		value, err := zk.GetOption(option[0])
+		if err != nil {
+			return err
+		}
+
+		if value != option[1] {
+			return fmt.Errorf("option %s is %q instead of %q", option[0], value, option[1])
+		}
+	}
+
+	return nil
+}
+
+func (zk *ZK) restartAndReconnect(reconnect bool) error {
+	if err := zk.Restart(); err != nil {
+		return err
+	}
+
+	if !reconnect {
+		return nil
+	}
+
+	deadline := time.Now().Add(RestartTimeout)
+	for {
+		time.Sleep(5 * time.Second)
+
+		err := zk.Connect()
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("can not reconnect to %s:%d: %s", zk.host, zk.port, err)
+		}
+	}
+}
+
+// writeOption writes a configuration parameter without making the device reload its configuration
+func (zk *ZK) writeOption(key, value string) error {
+	res, err := zk.sendCommand(CMD_OPTIONS_WRQ, []byte(key+"="+value+"\x00"), 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return fmt.Errorf("can not set option %s", key)
+	}
+
+	return nil
+}
+
+func (zk *ZK) refreshOption() error {
+	res, err := zk.sendCommand(CMD_REFRESHOPTION, nil, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can not refresh option")
+	}
+
+	return nil
+}
+
+// parseOption parses a NUL terminated key=value response
+func parseOption(data []byte) (string, string) {
+	option := cString(string(data))
+	if i := strings.IndexByte(option, '='); i >= 0 {
+		return option[:i], option[i+1:]
+	}
+	return option, ""
+}
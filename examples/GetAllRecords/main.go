@@ -33,7 +33,7 @@ func GetAllScannedEvents(tcp bool) {
 		fmt.Println("Number of events:", len(events))
 		now := time.Now()
 		for _, event := range events {
-			if event.Timestamp.Day() == 22 && event.Timestamp.Month() == now.Month() && event.Timestamp.Year() == now.Year() && event.UserID == 41 {
+			if event.Timestamp.Day() == 22 && event.Timestamp.Month() == now.Month() && event.Timestamp.Year() == now.Year() && event.UserID == "41" {
 				fmt.Println("Event:", event)
 			}
 		}
@@ -0,0 +1,312 @@
+package gozk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// GetTemplates returns all fingerprint templates stored on the connected device
+func (zk *ZK) GetTemplates() ([]*Finger, error) {
+	properties, err := zk.GetProperties()
+	if err != nil {
+		return nil, err
+	}
+
+	data, size, err := zk.readWithBuffer(CMD_DB_RRQ, FCT_FINGERTMP, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if size < 4 || properties.TotalFingers == 0 {
+		return []*Finger{}, nil
+	}
+
+	return zk.decodeTemplates(data)
+}
+
+// GetUserTemplates returns the fingerprint templates of the user by the given UID
+func (zk *ZK) GetUserTemplates(uid int) ([]*Finger, error) {
+	templates, err := zk.GetTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	fingers := []*Finger{}
+	for _, finger := range templates {
+		if finger.UID == uid {
+			fingers = append(fingers, finger)
+		}
+	}
+
+	return fingers, nil
+}
+
+// SaveUserTemplate creates or updates the user then uploads the given fingerprint templates
+func (zk *ZK) SaveUserTemplate(user *User, fingers ...*Finger) error {
+	return zk.SaveUsersWithTemplates([]*UserTemplates{{User: user, Fingers: fingers}})
+}
+
+// SaveUsersWithTemplates uploads many users with their fingerprint templates in a single transfer
+func (zk *ZK) SaveUsersWithTemplates(entries []*UserTemplates) error {
+	packetSize, err := zk.detectUserPacketSize()
+	if err != nil {
+		return err
+	}
+
+	buffer, err := zk.encodeUsersWithTemplates(entries, packetSize)
+	if err != nil {
+		return err
+	}
+
+	if err := zk.sendWithBuffer(buffer); err != nil {
+		return err
+	}
+
+	commandString := mustPack([]string{"I", "H", "H"}, []interface{}{12, 0, 8})
+	res, err := zk.sendCommand(CMD_SAVE_USERTEMPS, commandString, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can not save users with templates")
+	}
+
+	return zk.refreshData()
+}
+
+// encodeUsersWithTemplates builds the buffer uploaded by SaveUsersWithTemplates: the sizes of the three
+// sections, then the user records, the table locating each template, and the templates.
+func (zk *ZK) encodeUsersWithTemplates(entries []*UserTemplates, packetSize int) ([]byte, error) {
+	userPack := []byte{}
+	tablePack := []byte{}
+	fingerPack := []byte{}
+
+	for _, entry := range entries {
+		user, err := zk.encodeUser(entry.User, packetSize)
+		if err != nil {
+			return nil, err
+		}
+		userPack = append(userPack, 2)
+		userPack = append(userPack, user...)
+
+		for _, finger := range entry.Fingers {
+			tablePack = append(tablePack, mustPack([]string{"b", "H", "b", "I"}, []interface{}{2, entry.User.UID, 0x10 + finger.FingerID, len(fingerPack)})...)
+			fingerPack = append(fingerPack, mustPack([]string{"H"}, []interface{}{len(finger.Template)})...)
+			fingerPack = append(fingerPack, finger.Template...)
+		}
+	}
+
+	buffer := mustPack([]string{"I", "I", "I"}, []interface{}{len(userPack), len(tablePack), len(fingerPack)})
+	buffer = append(buffer, userPack...)
+	buffer = append(buffer, tablePack...)
+	buffer = append(buffer, fingerPack...)
+
+	return buffer, nil
+}
+
+// DeleteUserTemplate deletes a single fingerprint template of the user by the given UID
+func (zk *ZK) DeleteUserTemplate(uid, fingerIndex int) error {
+	commandString := mustPack([]string{"h", "b"}, []interface{}{uid, fingerIndex})
+	res, err := zk.sendCommand(CMD_DELETE_USERTEMP, commandString, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can not delete user template")
+	}
+
+	return zk.refreshData()
+}
+
+// HasTemplate reports whether the user by the given UID has a template enrolled for the finger
+func (zk *ZK) HasTemplate(uid, fingerIndex int) (bool, error) {
+	commandString := mustPack([]string{"h", "b"}, []interface{}{uid, fingerIndex})
+	res, err := zk.sendCommand(CMD_TEST_TEMP, commandString, 8)
+	if err != nil {
+		return false, err
+	}
+
+	return res.Status, nil
+}
+
+// Results carried by the EF_ENROLLFINGER events
+const (
+	enrollResultSuccess   = 0
+	enrollResultMismatch  = 4
+	enrollResultDuplicate = 5
+	enrollResultTimeout   = 6
+	enrollResultPress     = 0x64 // A press has been accepted, more are needed
+)
+
+// EnrollUser starts enrolling a finger of the user by the given UID on the device.
+// The user has to press the finger three times, the progress is reported through the returned channel
+// which is closed once the enrollment is over. Cancelling the context cancels the enrollment.
+func (zk *ZK) EnrollUser(ctx context.Context, uid, fingerIndex int) (<-chan *EnrollEvent, error) {
+	if zk.capturing != nil {
+		return nil, errors.New("already capturing")
+	}
+
+	if zk.enrolling {
+		return nil, errors.New("already enrolling")
+	}
+
+	users, err := zk.GetUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	var user *User
+	for _, u := range users {
+		if u.UID == uid {
+			user = u
+			break
+		}
+	}
+
+	if user == nil {
+		return nil, fmt.Errorf("user %d not found", uid)
+	}
+
+	var commandString []byte
+	if zk.tcp {
+		commandString = mustPack([]string{"24s", "b", "b"}, []interface{}{truncate(user.UserID, 24), fingerIndex, 1})
+	} else {
+		userID, err := strconv.Atoi(user.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("user_id %q must be numeric over UDP", user.UserID)
+		}
+		commandString = mustPack([]string{"I", "b"}, []interface{}{userID, fingerIndex})
+	}
+
+	if err := zk.cancelCapture(); err != nil {
+		return nil, err
+	}
+
+	if err := zk.regEvent(EF_ENROLLFINGER | EF_FINGER); err != nil {
+		return nil, err
+	}
+
+	res, err := zk.sendCommand(CMD_STARTENROLL, commandString, 8)
+	if err != nil {
+		zk.regEvent(0)
+		return nil, err
+	}
+
+	if !res.Status {
+		zk.regEvent(0)
+		return nil, fmt.Errorf("can not enroll user %d finger %d", uid, fingerIndex)
+	}
+
+	logrus.Info("Start enrolling uid:", uid, " finger:", fingerIndex)
+	zk.enrolling = true
+	events := make(chan *EnrollEvent, 1)
+
+	go func() {
+		defer func() {
+			zk.enrolling = false
+			zk.regEvent(0)
+			zk.cancelCapture()
+			zk.verifyUser()
+			close(events)
+			logrus.Info("Stopped enrolling")
+		}()
+
+		send := func(event *EnrollEvent) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		}
+
+		state := &enrollment{uid: uid, fingerID: fingerIndex}
+		deadline := time.Now().Add(EnrollmentTimeout)
+
+		for {
+			select {
+			case <-ctx.Done():
+				select {
+				case events <- state.event(EnrollCancelled, ctx.Err()):
+				default:
+				}
+				return
+			default:
+			}
+
+			if time.Now().After(deadline) {
+				send(state.event(EnrollTimeout, nil))
+				return
+			}
+
+			flag, data, err := zk.receiveEvent(time.Second)
+			if err != nil {
+				if strings.Contains(err.Error(), "timeout") {
+					continue
+				}
+				send(state.event(EnrollFailed, err))
+				return
+			}
+
+			event := state.decode(flag, data)
+			if event == nil {
+				continue
+			}
+
+			send(event)
+			if event.Status != EnrollFingerPressed && event.Status != EnrollPressAccepted {
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// enrollment tracks a remote enrollment from the events pushed by the device
+type enrollment struct {
+	uid      int
+	fingerID int
+	presses  int
+}
+
+func (e *enrollment) event(status EnrollStatus, err error) *EnrollEvent {
+	return &EnrollEvent{UID: e.uid, FingerID: e.fingerID, Status: status, Presses: e.presses, Error: err}
+}
+
+// decode turns an event pushed by the device into an EnrollEvent, nil if there is nothing to report.
+// Any status other than EnrollFingerPressed and EnrollPressAccepted ends the enrollment.
+func (e *enrollment) decode(flag int, data []byte) *EnrollEvent {
+	switch flag {
+	case EF_FINGER:
+		return e.event(EnrollFingerPressed, nil)
+	case EF_ENROLLFINGER:
+		if len(data) < 2 {
+			return nil
+		}
+
+		switch result := mustUnpack([]string{"H"}, data[:2])[0].(int); result {
+		case enrollResultPress:
+			e.presses++
+			return e.event(EnrollPressAccepted, nil)
+		case enrollResultSuccess:
+			return e.event(EnrollSuccess, nil)
+		case enrollResultMismatch:
+			return e.event(EnrollFailed, errors.New("the finger presses do not match"))
+		case enrollResultDuplicate:
+			return e.event(EnrollDuplicate, nil)
+		case enrollResultTimeout:
+			return e.event(EnrollTimeout, nil)
+		default:
+			return e.event(EnrollFailed, fmt.Errorf("enrollment failed with code %d", result))
+		}
+	default:
+		return nil
+	}
+}
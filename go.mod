@@ -4,6 +4,7 @@ require (
 	github.com/canhlinh/go-binary-pack v0.0.0-20181203110405-72348cf47f32
 	github.com/sirupsen/logrus v1.9.3
 	github.com/stretchr/testify v1.10.0
+	golang.org/x/text v0.3.8
 )
 
 go 1.13
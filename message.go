@@ -0,0 +1,129 @@
+package gozk
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	messageRecordSize  = 172
+	messageContentSize = 161
+)
+
+// GetMessages returns the short messages stored on the device
+func (zk *ZK) GetMessages() ([]*Message, error) {
+	data, size, err := zk.readWithBuffer(CMD_SMS_RRQ, FCT_SMS, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if size < 4 {
+		return []*Message{}, nil
+	}
+
+	return zk.decodeMessages(data[4:])
+}
+
+// SetMessage creates a short message or updates the one with the same ID
+func (zk *ZK) SetMessage(message *Message) error {
+	commandString, err := zk.encodeMessage(message)
+	if err != nil {
+		return err
+	}
+
+	res, err := zk.sendCommand(CMD_SMS_WRQ, commandString, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return fmt.Errorf("can not set message %d", message.ID)
+	}
+
+	return zk.refreshData()
+}
+
+// DeleteMessage deletes the short message by the given ID
+func (zk *ZK) DeleteMessage(id int) error {
+	commandString := mustPack([]string{"H"}, []interface{}{id})
+	res, err := zk.sendCommand(CMD_DELETE_SMS, commandString, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return fmt.Errorf("can not delete message %d", id)
+	}
+
+	return zk.refreshData()
+}
+
+// AssignMessageToUser shows the personal message by the given ID to the user by the given UID when it punches
+func (zk *ZK) AssignMessageToUser(uid, id int) error {
+	commandString := mustPack([]string{"H", "H"}, []interface{}{uid, id})
+	res, err := zk.sendCommand(CMD_UDATA_WRQ, commandString, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can not assign message")
+	}
+
+	return zk.refreshData()
+}
+
+// UnassignMessage stops showing the personal message by the given ID to the user by the given UID
+func (zk *ZK) UnassignMessage(uid, id int) error {
+	commandString := mustPack([]string{"H", "H"}, []interface{}{uid, id})
+	res, err := zk.sendCommand(CMD_DELETE_UDATA, commandString, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can not unassign message")
+	}
+
+	return zk.refreshData()
+}
+
+// decodeMessages decodes the message records made of the tag, ID, validity, a reserved field,
+// the start time and the NUL terminated content.
+func (zk *ZK) decodeMessages(data []byte) ([]*Message, error) {
+	messages := []*Message{}
+
+	for len(data) >= messageRecordSize {
+		v, err := unpack([]string{"B", "H", "H", "H", "4s", fmt.Sprintf("%ds", messageContentSize)}, data[:messageRecordSize])
+		if err != nil {
+			return nil, err
+		}
+
+		startTime, err := zk.decodeTime([]byte(v[4].(string)))
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, &Message{
+			Tag:          MessageTag(v[0].(int)),
+			ID:           v[1].(int),
+			ValidMinutes: v[2].(int),
+			StartTime:    startTime,
+			Content:      zk.decodeText(v[5].(string)),
+		})
+		data = data[messageRecordSize:]
+	}
+
+	return messages, nil
+}
+
+func (zk *ZK) encodeMessage(message *Message) ([]byte, error) {
+	return newBP().Pack([]string{"B", "H", "H", "H", "I", fmt.Sprintf("%ds", messageContentSize)}, []interface{}{
+		int(message.Tag),
+		message.ID,
+		message.ValidMinutes,
+		0,
+		zk.encodeTime(message.StartTime.In(zk.loc)),
+		string(zk.encodeText(message.Content, messageContentSize-1)),
+	})
+}
@@ -0,0 +1,31 @@
+package gozk
+
+import "errors"
+
+// GetOperationLog returns the operations done on the terminal, like enrolling users or entering the menu
+func (zk *ZK) GetOperationLog() ([]*OperationLog, error) {
+	data, size, err := zk.readWithBuffer(CMD_OPLOG_RRQ, FCT_OPLOG, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if size < 4 {
+		return []*OperationLog{}, nil
+	}
+
+	return zk.decodeOperationLog(data[4:])
+}
+
+// ClearOperationLog deletes all operation records of the device
+func (zk *ZK) ClearOperationLog() error {
+	res, err := zk.sendCommand(CMD_CLEAR_OPLOG, nil, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can not clear operation log")
+	}
+
+	return nil
+}
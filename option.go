@@ -1,6 +1,12 @@
 package gozk
 
-import "time"
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
 
 // Option specifies the task processing behavior.
 type Option interface {
@@ -19,6 +25,8 @@ const (
 	OptionTimezone
 	OptionUseTCP
 	OptionDeviceID
+	OptionUnknownCardCapture
+	OptionEncoding
 )
 
 type optionPort int
@@ -95,6 +103,46 @@ func WithDeviceID(deviceID string) Option {
 	return optionDeviceID(deviceID)
 }
 
+type optionUnknownCardCapture bool
+
+func (o optionUnknownCardCapture) Type() OptionType {
+	return OptionUnknownCardCapture
+}
+
+func (o optionUnknownCardCapture) Value() interface{} {
+	return bool(o)
+}
+
+// WithUnknownCardCapture makes StartCapturing report swipes of cards that are not assigned to any user
+func WithUnknownCardCapture(enabled bool) Option {
+	return optionUnknownCardCapture(enabled)
+}
+
+type optionEncoding struct {
+	encoding encoding.Encoding
+}
+
+func (o optionEncoding) Type() OptionType {
+	return OptionEncoding
+}
+
+func (o optionEncoding) Value() interface{} {
+	return o.encoding
+}
+
+// WithEncoding sets the character set used by the device for user names, LCD and message texts,
+// like "utf-8", "gbk", "windows-1252" or "windows-1258". Texts are sent as is by default,
+// which is also what happens with a warning if the charset is unknown.
+func WithEncoding(charset string) Option {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		logrus.Warnf("Unknown encoding %q, texts are sent as is: %s", charset, err)
+		return optionEncoding{}
+	}
+
+	return optionEncoding{encoding: enc}
+}
+
 type option struct {
 	port     int
 	pin      int
@@ -102,6 +150,9 @@ type option struct {
 	useTCP   bool
 	deviceID string
 	maxChunk int
+
+	unknownCardCapture bool
+	encoding           encoding.Encoding
 }
 
 func composeOption(opts ...Option) *option {
@@ -128,6 +179,10 @@ func composeOption(opts ...Option) *option {
 			}
 		case OptionDeviceID:
 			opt.deviceID = o.Value().(string)
+		case OptionUnknownCardCapture:
+			opt.unknownCardCapture = o.Value().(bool)
+		case OptionEncoding:
+			opt.encoding, _ = o.Value().(encoding.Encoding)
 		}
 	}
 
@@ -2,6 +2,7 @@ package gozk
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 )
 
@@ -15,17 +16,433 @@ type Response struct {
 }
 
 type User struct {
+	UID       int    // An internal index of the user on the device
+	UserID    string // An unique identifier for the user
+	Name      string // The name shown on the device
+	Privilege int    // One of USER_DEFAULT, USER_ENROLLER, USER_MANAGER or USER_ADMIN
+	Password  string // The password used to verify the user
+	GroupID   string // The group the user belongs to
+	Card      uint32 // The RFID card number, 0 if no card is assigned
+}
+
+type Finger struct {
+	UID      int    // The internal index of the user owning the template
+	FingerID int    // The finger index, from 0 to 9
+	Valid    int    // 1 for a valid template, 3 for a duress finger, 0 if disabled
+	Template []byte // The raw template data
+}
+
+// UserTemplates pairs a user with the fingerprint templates to upload for it
+type UserTemplates struct {
+	User    *User
+	Fingers []*Finger
+}
+
+func (finger Finger) String() string {
+	return fmt.Sprintf("uid:%d finger_id:%d valid:%d size:%d", finger.UID, finger.FingerID, finger.Valid, len(finger.Template))
+}
+
+// EnrollStatus describes a step of a remote enrollment
+type EnrollStatus int
+
+const (
+	EnrollFingerPressed EnrollStatus = iota
+	EnrollPressAccepted
+	EnrollSuccess
+	EnrollDuplicate
+	EnrollTimeout
+	EnrollFailed
+	EnrollCancelled
+)
+
+func (s EnrollStatus) String() string {
+	switch s {
+	case EnrollFingerPressed:
+		return "finger-pressed"
+	case EnrollPressAccepted:
+		return "press-accepted"
+	case EnrollSuccess:
+		return "success"
+	case EnrollDuplicate:
+		return "duplicate"
+	case EnrollTimeout:
+		return "timeout"
+	case EnrollFailed:
+		return "failed"
+	case EnrollCancelled:
+		return "cancelled"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+type EnrollEvent struct {
+	UID      int          // The internal index of the user being enrolled
+	FingerID int          // The finger index being enrolled
+	Status   EnrollStatus // The step of the enrollment
+	Presses  int          // How many presses the device has accepted so far
+	Error    error        // An error if the enrollment has been interrupted
+}
+
+func (event EnrollEvent) String() string {
+	return fmt.Sprintf("uid:%d finger_id:%d status:%s presses:%d", event.UID, event.FingerID, event.Status, event.Presses)
+}
+
+// OperationCode describes an operation done on the terminal itself
+type OperationCode int
+
+const (
+	OpPowerOn OperationCode = iota
+	OpPowerOff
+	OpVerifyFailed
+	OpAlarm
+	OpEnterMenu
+	OpChangeSettings
+	OpEnrollFinger
+	OpEnrollPassword
+	OpEnrollCard
+	OpDeleteUser
+	OpDeleteFinger
+	OpDeletePassword
+	OpDeleteCard
+	OpClearData
+	OpCreateMifareCard
+	OpEnrollMifareCard
+	OpRegisterMifareCard
+	OpDeleteMifareRegistration
+	OpClearMifareCard
+	OpMoveDataToCard
+	OpCopyDataToCard
+	OpSetTime
+	OpFactoryReset
+	OpDeleteAttendance
+	OpClearAdmins
+	OpModifyAccessGroup
+	OpModifyUserAccess
+	OpModifyTimeZone
+	OpModifyUnlockCombination
+	OpUnlock
+	OpEnrollUser
+	OpChangeFingerAttribute
+	OpDuressAlarm
+)
+
+func (op OperationCode) String() string {
+	switch op {
+	case OpPowerOn:
+		return "power-on"
+	case OpPowerOff:
+		return "power-off"
+	case OpVerifyFailed:
+		return "verify-failed"
+	case OpAlarm:
+		return "alarm"
+	case OpEnterMenu:
+		return "enter-menu"
+	case OpChangeSettings:
+		return "change-settings"
+	case OpEnrollFinger:
+		return "enroll-finger"
+	case OpEnrollPassword:
+		return "enroll-password"
+	case OpEnrollCard:
+		return "enroll-card"
+	case OpDeleteUser:
+		return "delete-user"
+	case OpDeleteFinger:
+		return "delete-finger"
+	case OpDeletePassword:
+		return "delete-password"
+	case OpDeleteCard:
+		return "delete-card"
+	case OpClearData:
+		return "clear-data"
+	case OpCreateMifareCard:
+		return "create-mifare-card"
+	case OpEnrollMifareCard:
+		return "enroll-mifare-card"
+	case OpRegisterMifareCard:
+		return "register-mifare-card"
+	case OpDeleteMifareRegistration:
+		return "delete-mifare-registration"
+	case OpClearMifareCard:
+		return "clear-mifare-card"
+	case OpMoveDataToCard:
+		return "move-data-to-card"
+	case OpCopyDataToCard:
+		return "copy-data-to-card"
+	case OpSetTime:
+		return "set-time"
+	case OpFactoryReset:
+		return "factory-reset"
+	case OpDeleteAttendance:
+		return "delete-attendance"
+	case OpClearAdmins:
+		return "clear-admins"
+	case OpModifyAccessGroup:
+		return "modify-access-group"
+	case OpModifyUserAccess:
+		return "modify-user-access"
+	case OpModifyTimeZone:
+		return "modify-time-zone"
+	case OpModifyUnlockCombination:
+		return "modify-unlock-combination"
+	case OpUnlock:
+		return "unlock"
+	case OpEnrollUser:
+		return "enroll-user"
+	case OpChangeFingerAttribute:
+		return "change-finger-attribute"
+	case OpDuressAlarm:
+		return "duress-alarm"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(op))
+	}
+}
+
+type OperationLog struct {
+	DeviceID  string        // An unique identifier for the device
+	AdminID   int           // The UID of the admin who did the operation, 0 if none
+	Operation OperationCode // What has been done
+	Timestamp time.Time     // The time when the operation has been done
+	Params    [4]int        // The operation parameters, most of the time the affected user
+}
+
+func (log OperationLog) String() string {
+	return fmt.Sprintf("device_id:%s admin_id:%d operation:%s at:%v params:%v", log.DeviceID, log.AdminID, log.Operation, log.Timestamp.Format(time.RFC3339), log.Params)
+}
+
+// DeviceState describes what the device is currently doing
+type DeviceState int
+
+const (
+	DeviceIdle DeviceState = iota
+	DeviceEnrolling
+	DeviceVerifying
+	DeviceInMenu
+	DeviceBusy
+	DeviceWaitingCard
+)
+
+func (s DeviceState) String() string {
+	switch s {
+	case DeviceIdle:
+		return "idle"
+	case DeviceEnrolling:
+		return "enrolling"
+	case DeviceVerifying:
+		return "verifying"
+	case DeviceInMenu:
+		return "in-menu"
+	case DeviceBusy:
+		return "busy"
+	case DeviceWaitingCard:
+		return "waiting-card"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+type DeviceStatus struct {
+	DeviceID string      // An unique identifier for the device
+	State    DeviceState // What the device is currently doing
+}
+
+// DoorState describes the state reported by the door sensor
+type DoorState int
+
+const (
+	DoorClosed DoorState = iota
+	DoorOpen
+	DoorForced
+)
+
+func (s DoorState) String() string {
+	switch s {
+	case DoorClosed:
+		return "closed"
+	case DoorOpen:
+		return "open"
+	case DoorForced:
+		return "forced"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+type DoorStatus struct {
+	DeviceID string    // An unique identifier for the device
+	State    DoorState // Whether the door is closed, open or has been forced open
+}
+
+// TimeWindow is a daily period during which the access is granted
+type TimeWindow struct {
+	StartHour   int `json:"start_hour"`
+	StartMinute int `json:"start_minute"`
+	EndHour     int `json:"end_hour"`
+	EndMinute   int `json:"end_minute"`
+}
+
+// Contains reports whether the time of the day of t is inside the window, a zero window means the day is closed
+func (w TimeWindow) Contains(t time.Time) bool {
+	if w == (TimeWindow{}) {
+		return false
+	}
+
+	minutes := t.Hour()*60 + t.Minute()
+	return minutes >= w.StartHour*60+w.StartMinute && minutes <= w.EndHour*60+w.EndMinute
+}
+
+// AccessTimeZone is an access control time table, it tells when the door can be opened for each day of the week
+type AccessTimeZone struct {
+	ID      int                         `json:"id"`
+	Windows map[time.Weekday]TimeWindow `json:"windows"`
+}
+
+// UserTimeZone assigns up to three time zones to a user
+type UserTimeZone struct {
+	UID       int    `json:"uid"`
+	UseGroup  bool   `json:"use_group"`  // The user follows the time zones of its group instead of its own
+	TimeZones [3]int `json:"time_zones"` // The IDs of the assigned time zones, 0 if unused
+}
+
+// GroupTimeZone assigns up to three time zones to a group of users
+type GroupTimeZone struct {
+	Group     int    `json:"group"`
+	TimeZones [3]int `json:"time_zones"` // The IDs of the assigned time zones, 0 if unused
+}
+
+// UnlockCombination requires a user of each listed group to verify before the door unlocks
+type UnlockCombination struct {
+	ID     int   `json:"id"`     // From 1 to 10
+	Groups []int `json:"groups"` // Up to 5 group IDs, the same group may be listed many times
+}
+
+// AccessControl is a snapshot of the whole access control configuration of a device
+type AccessControl struct {
+	TimeZones          []*AccessTimeZone    `json:"time_zones"`
+	GroupTimeZones     []*GroupTimeZone     `json:"group_time_zones"`
+	UserTimeZones      []*UserTimeZone      `json:"user_time_zones"`
+	UserGroups         map[int]int          `json:"user_groups"` // The group of each user, by UID
+	UnlockCombinations []*UnlockCombination `json:"unlock_combinations"`
+}
+
+// MessageTag tells who a short message is shown to
+type MessageTag int
+
+const (
+	MessagePublic   MessageTag = 253 // Shown to everyone
+	MessagePersonal MessageTag = 254 // Shown to the users it is assigned to
+	MessageReserved MessageTag = 255
+)
+
+func (tag MessageTag) String() string {
+	switch tag {
+	case MessagePublic:
+		return "public"
+	case MessagePersonal:
+		return "personal"
+	case MessageReserved:
+		return "reserved"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(tag))
+	}
+}
+
+// Message is a short message shown on the terminal
+type Message struct {
+	ID           int        // An unique identifier for the message
+	Tag          MessageTag // Who the message is shown to
+	StartTime    time.Time  // When the message starts to be shown
+	ValidMinutes int        // How long the message is shown, 0 means forever
+	Content      string     // The text of the message, up to 160 bytes once encoded for the device
+}
+
+func (message Message) String() string {
+	return fmt.Sprintf("id:%d tag:%s start:%v valid_minutes:%d content:%q", message.ID, message.Tag, message.StartTime.Format(time.RFC3339), message.ValidMinutes, message.Content)
+}
+
+// VerifyType describes how the user has been verified by the device
+type VerifyType int
+
+const (
+	VerifyPassword    VerifyType = 0
+	VerifyFingerprint VerifyType = 1
+	VerifyCard        VerifyType = 2
+	VerifyFace        VerifyType = 15
+)
+
+func (v VerifyType) String() string {
+	switch v {
+	case VerifyPassword:
+		return "password"
+	case VerifyFingerprint:
+		return "fingerprint"
+	case VerifyCard:
+		return "card"
+	case VerifyFace:
+		return "face"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(v))
+	}
+}
+
+// PunchState describes the attendance state chosen by the user when punching
+type PunchState int
+
+const (
+	PunchCheckIn  PunchState = 0
+	PunchCheckOut PunchState = 1
+	PunchBreakOut PunchState = 2
+	PunchBreakIn  PunchState = 3
+	PunchOTIn     PunchState = 4
+	PunchOTOut    PunchState = 5
+)
+
+func (p PunchState) String() string {
+	switch p {
+	case PunchCheckIn:
+		return "check-in"
+	case PunchCheckOut:
+		return "check-out"
+	case PunchBreakOut:
+		return "break-out"
+	case PunchBreakIn:
+		return "break-in"
+	case PunchOTIn:
+		return "ot-in"
+	case PunchOTOut:
+		return "ot-out"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(p))
+	}
 }
 
 type ScanEvent struct {
-	DeviceID  string    // An unique identifier for the device
-	UserID    int64     // An unique identifier for the user
-	Timestamp time.Time // The time when the event was scanned
-	Error     error     // An error if the event is invalid
+	DeviceID   string     // An unique identifier for the device
+	UserID     string     // An unique identifier for the user
+	Timestamp  time.Time  // The time when the event was scanned
+	VerifyType VerifyType // How the user has been verified
+	PunchState PunchState // Check-in, check-out, break or overtime
+	WorkCode   int        // The work code entered by the user, 0 if none
+	Card       uint32     // The number of a swiped card not assigned to any user, see WithUnknownCardCapture
+	Error      error      // An error if the event is invalid
+}
+
+// NumericUserID returns the user ID as a number, for devices enrolling numeric IDs only
+func (event ScanEvent) NumericUserID() (int64, error) {
+	return strconv.ParseInt(event.UserID, 10, 64)
 }
 
 func (event ScanEvent) String() string {
-	return fmt.Sprintf("device_id:%s user_id:%d at:%v", event.DeviceID, event.UserID, event.Timestamp.Format(time.RFC3339))
+	if event.Card != 0 {
+		return fmt.Sprintf("device_id:%s unknown_card:%d at:%v", event.DeviceID, event.Card, event.Timestamp.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("device_id:%s user_id:%s at:%v verify:%s punch:%s work_code:%d", event.DeviceID, event.UserID, event.Timestamp.Format(time.RFC3339), event.VerifyType, event.PunchState, event.WorkCode)
+}
+
+func (user User) String() string {
+	return fmt.Sprintf("uid:%d user_id:%s name:%s privilege:%d group_id:%s card:%d", user.UID, user.UserID, user.Name, user.Privilege, user.GroupID, user.Card)
 }
 
 func (r Response) String() string {
@@ -4,7 +4,9 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	binarypack "github.com/canhlinh/go-binary-pack"
 )
@@ -265,3 +267,31 @@ func ljust(data []byte, len int) []byte {
 	copy(result, data)
 	return result
 }
+
+// cString returns the content of a NUL terminated string
+func cString(s string) string {
+	if i := strings.IndexByte(s, 0); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// truncateText cuts the UTF-8 string down to at most size bytes without splitting a character
+func truncateText(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+
+	for size > 0 && !utf8.RuneStart(s[size]) {
+		size--
+	}
+	return s[:size]
+}
+
+// truncate cuts the string down to size bytes so that it fits in a fixed size field
+func truncate(s string, size int) string {
+	if len(s) > size {
+		return s[:size]
+	}
+	return s
+}
@@ -2,12 +2,13 @@ package gozk
 
 import (
 	"errors"
+	"fmt"
 	"net"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"golang.org/x/text/encoding"
 )
 
 const (
@@ -17,6 +18,9 @@ const (
 var (
 	KeepAlivePeriod   = time.Minute
 	ReadSocketTimeout = 3 * time.Second
+	EnrollmentTimeout = time.Minute
+	RestartTimeout    = 2 * time.Minute
+	CardTimeout       = 30 * time.Second
 )
 
 type ZK struct {
@@ -30,8 +34,13 @@ type ZK struct {
 	loc       *time.Location
 	disabled  bool
 	capturing chan bool
+	enrolling bool
 	deviceID  string
 	maxChunk  int
+
+	userPacketSize     int
+	unknownCardCapture bool
+	encoding           encoding.Encoding
 }
 
 func NewZK(host string, opts ...Option) *ZK {
@@ -46,6 +55,9 @@ func NewZK(host string, opts ...Option) *ZK {
 		deviceID:  option.deviceID,
 		tcp:       option.useTCP,
 		maxChunk:  option.maxChunk,
+
+		unknownCardCapture: option.unknownCardCapture,
+		encoding:           option.encoding,
 	}
 }
 
@@ -60,6 +72,7 @@ func (zk *ZK) Connect() error {
 	zk.conn = conn
 	res, err := zk.sendCommand(CMD_CONNECT, nil, 8)
 	if err != nil {
+		zk.resetConnection()
 		return err
 	}
 
@@ -69,10 +82,12 @@ func (zk *ZK) Connect() error {
 		commandString, _ := makeCommKey(zk.pin, zk.sessionID, 50)
 		res, err := zk.sendCommand(CMD_AUTH, commandString, 8)
 		if err != nil {
+			zk.resetConnection()
 			return err
 		}
 
 		if !res.Status {
+			zk.resetConnection()
 			return errors.New("unauthorized")
 		}
 	}
@@ -100,6 +115,44 @@ func (zk *ZK) Disconnect() error {
 	return nil
 }
 
+// Restart restarts the device. The connection is closed, call Connect again once the device is back
+func (zk *ZK) Restart() error {
+	return zk.shutdown(CMD_RESTART)
+}
+
+// PowerOff shuts the device down. The connection is closed
+func (zk *ZK) PowerOff() error {
+	return zk.shutdown(CMD_POWEROFF)
+}
+
+// Sleep puts the device in the idle state
+func (zk *ZK) Sleep() error {
+	res, err := zk.sendCommand(CMD_SLEEP, nil, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can not put the device to sleep")
+	}
+
+	return nil
+}
+
+// Resume wakes the device up from the idle state
+func (zk *ZK) Resume() error {
+	res, err := zk.sendCommand(CMD_RESUME, nil, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can not resume the device")
+	}
+
+	return nil
+}
+
 // EnableDevice enables the connected device
 func (zk *ZK) EnableDevice() error {
 
@@ -131,6 +184,49 @@ func (zk *ZK) DisableDevice() error {
 	return nil
 }
 
+// ClearAllData wipes users, fingerprint templates, attendance and operation records of the device
+func (zk *ZK) ClearAllData() error {
+	res, err := zk.sendCommand(CMD_CLEAR_DATA, nil, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can not clear data")
+	}
+
+	return zk.refreshData()
+}
+
+// ClearData wipes a single kind of data of the device, kind is one of the FCT_* constants
+func (zk *ZK) ClearData(kind int) error {
+	commandString := mustPack([]string{"B"}, []interface{}{kind})
+	res, err := zk.sendCommand(CMD_CLEAR_DATA, commandString, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return fmt.Errorf("can not clear data of kind %d", kind)
+	}
+
+	return zk.refreshData()
+}
+
+// ClearAdmins removes the admin privilege of all users, which gives back access to the device menu
+func (zk *ZK) ClearAdmins() error {
+	res, err := zk.sendCommand(CMD_CLEAR_ADMIN, nil, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can not clear admins")
+	}
+
+	return zk.refreshData()
+}
+
 // GetAllScannedEvents returns total attendances from the connected device
 func (zk *ZK) GetAllScannedEvents() ([]*ScanEvent, error) {
 	properties, err := zk.GetProperties()
@@ -146,54 +242,154 @@ func (zk *ZK) GetAllScannedEvents() ([]*ScanEvent, error) {
 		return []*ScanEvent{}, nil
 	}
 
+	if properties.TotalRecords == 0 {
+		return []*ScanEvent{}, nil
+	}
+
 	totalSizeByte := data[:4]
 	data = data[4:]
 
 	totalSize := mustUnpack([]string{"I"}, totalSizeByte)[0].(int)
 	recordSize := totalSize / properties.TotalRecords
 
-	attendances := []*ScanEvent{}
-	if recordSize == 8 || recordSize == 16 {
-		return nil, errors.New("sorry but I'm too lazy to implement this")
+	// The 8 bytes layout only carries the UID, the user table is needed to resolve the user ID.
+	users := []*User{}
+	if recordSize == 8 {
+		if users, err = zk.GetUsers(); err != nil {
+			return nil, err
+		}
 	}
 
-	for len(data) >= 40 {
+	return zk.decodeAttendances(data, recordSize, users)
+}
 
-		v, err := newBP().UnPack([]string{"H", "24s", "B", "4s", "B", "8s"}, ljust(data, 40))
-		if err != nil {
-			return nil, err
-		}
+// ClearAttendance deletes all attendance records of the device
+func (zk *ZK) ClearAttendance() error {
+	res, err := zk.sendCommand(CMD_CLEAR_ATTLOG, nil, 8)
+	if err != nil {
+		return err
+	}
 
-		timestamp, err := zk.decodeTime([]byte(v[3].(string)))
-		if err != nil {
-			return nil, err
-		}
+	if !res.Status {
+		return errors.New("can not clear attendance")
+	}
 
-		userID, err := strconv.ParseInt(strings.ReplaceAll(v[1].(string), "\x00", ""), 10, 64)
-		if err != nil {
-			return nil, err
+	return nil
+}
+
+// ClearAttendanceAfterExport downloads all attendance records, hands them to export then clears them.
+// The device is disabled meanwhile, and nothing is cleared if export fails or if the number of records
+// changed in between, so no punch can be lost.
+func (zk *ZK) ClearAttendanceAfterExport(export func([]*ScanEvent) error) (err error) {
+	if !zk.disabled {
+		if err := zk.DisableDevice(); err != nil {
+			return err
 		}
-		attendances = append(attendances, &ScanEvent{DeviceID: zk.deviceID, Timestamp: timestamp, UserID: userID})
-		data = data[40:]
+		defer func() {
+			if enableErr := zk.EnableDevice(); err == nil {
+				err = enableErr
+			}
+		}()
+	}
+
+	properties, err := zk.GetProperties()
+	if err != nil {
+		return err
+	}
+
+	events, err := zk.GetAllScannedEvents()
+	if err != nil {
+		return err
+	}
+
+	if len(events) != properties.TotalRecords {
+		return fmt.Errorf("downloaded %d of %d attendance records", len(events), properties.TotalRecords)
+	}
+
+	if err := export(events); err != nil {
+		return err
+	}
+
+	properties, err = zk.GetProperties()
+	if err != nil {
+		return err
+	}
+
+	if properties.TotalRecords != len(events) {
+		return fmt.Errorf("attendance records changed during export: %d before, %d after", len(events), properties.TotalRecords)
+	}
+
+	return zk.ClearAttendance()
+}
+
+// GetUsers returns all users enrolled on the connected device
+func (zk *ZK) GetUsers() ([]*User, error) {
+	properties, err := zk.GetProperties()
+	if err != nil {
+		return nil, err
+	}
+
+	data, size, err := zk.readWithBuffer(CMD_USERTEMP_RRQ, FCT_USER, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	totalSize := 0
+	if size >= 4 {
+		totalSize = mustUnpack([]string{"I"}, data[:4])[0].(int)
+		data = data[4:]
 	}
 
-	return attendances, nil
+	if properties.TotalUsers == 0 {
+		return []*User{}, nil
+	}
+
+	packetSize, err := userPacketSize(totalSize, properties.TotalUsers, zk.tcp)
+	if err != nil {
+		return nil, err
+	}
+	zk.userPacketSize = packetSize
+
+	return zk.decodeUsers(data, zk.userPacketSize)
 }
 
-// GetUsers returns a list of users
-// For now, just run this func. I'll implement this function later on.
-func (zk *ZK) GetUsers() error {
+// SetUser creates a new user or updates an existing one with the same UID
+func (zk *ZK) SetUser(user *User) error {
+	packetSize, err := zk.detectUserPacketSize()
+	if err != nil {
+		return err
+	}
+
+	commandString, err := zk.encodeUser(user, packetSize)
+	if err != nil {
+		return err
+	}
 
-	_, size, err := zk.readWithBuffer(CMD_USERTEMP_RRQ, FCT_USER, 0)
+	res, err := zk.sendCommand(CMD_USER_WRQ, commandString, 1024)
 	if err != nil {
 		return err
 	}
 
-	if size < 4 {
-		return nil
+	if !res.Status {
+		return errors.New("can not set user")
 	}
 
-	return nil
+	return zk.refreshData()
+}
+
+// DeleteUser deletes the user by the given UID
+func (zk *ZK) DeleteUser(uid int) error {
+	commandString := mustPack([]string{"h"}, []interface{}{uid})
+	res, err := zk.sendCommand(CMD_DELETE_USER, commandString, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can not delete user")
+	}
+
+	return zk.refreshData()
 }
 
 func (zk *ZK) StartCapturing(outerChan chan<- *ScanEvent) error {
@@ -205,11 +401,29 @@ func (zk *ZK) StartCapturing(outerChan chan<- *ScanEvent) error {
 		return errors.New("device is disabled")
 	}
 
+	// The user table can't be read while capturing,
+	// so the known cards are loaded beforehand to recognize unknown swipes.
+	flags := EF_ATTLOG
+	knownCards := map[uint32]bool{}
+	if zk.unknownCardCapture {
+		users, err := zk.GetUsers()
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			if user.Card != 0 {
+				knownCards[user.Card] = true
+			}
+		}
+		flags |= EF_HIDNUM
+	}
+
 	if err := zk.verifyUser(); err != nil {
 		return err
 	}
 
-	if err := zk.regEvent(EF_ATTLOG); err != nil {
+	if err := zk.regEvent(flags); err != nil {
 		return err
 	}
 
@@ -248,44 +462,25 @@ func (zk *ZK) StartCapturing(outerChan chan<- *ScanEvent) error {
 					continue
 				}
 
-				var header []interface{}
-				if zk.tcp {
-					header = mustUnpack([]string{"H", "H", "H", "H"}, data[8:16])
-					data = data[16:]
-				} else {
-					header = mustUnpack([]string{"H", "H", "H", "H"}, data[:8])
-					data = data[8:]
+				code, flag, data, err := decodePacket(data, zk.tcp)
+				if err != nil {
+					logrus.Warn("Skipped a live event: ", err)
+					continue
 				}
 
-				if header[0].(int) != CMD_REG_EVENT {
+				if code != CMD_REG_EVENT {
 					continue
 				}
 
-				for len(data) >= 12 {
-					unpack := []interface{}{}
-
-					if len(data) == 12 {
-						unpack = mustUnpack([]string{"I", "B", "B", "6s"}, data)
-						data = data[12:]
-					} else if len(data) == 32 {
-						unpack = mustUnpack([]string{"24s", "B", "B", "6s"}, data[:32])
-						data = data[32:]
-					} else if len(data) == 36 {
-						unpack = mustUnpack([]string{"24s", "B", "B", "6s", "4s"}, data[:36])
-						data = data[36:]
-					} else if len(data) >= 52 {
-						unpack = mustUnpack([]string{"24s", "B", "B", "6s", "20s"}, data[:52])
-						data = data[52:]
+				if flag == EF_HIDNUM {
+					if event := zk.decodeUnknownCard(data, knownCards); event != nil {
+						outerChan <- event
+						logrus.Println("ScanEvent", event.String())
 					}
+					continue
+				}
 
-					timestamp := zk.decodeTimeHex([]byte(unpack[3].(string)))
-
-					userID, err := strconv.ParseInt(strings.Replace(unpack[0].(string), "\x00", "", -1), 10, 64)
-					if err != nil {
-						onConnectionError(err)
-						return
-					}
-					event := &ScanEvent{DeviceID: zk.deviceID, UserID: userID, Timestamp: timestamp}
+				for _, event := range zk.decodeLiveEvents(data) {
 					outerChan <- event
 					logrus.Println("ScanEvent", event.String())
 				}
@@ -315,6 +510,9 @@ func (zk ZK) Clone() *ZK {
 		capturing: nil,
 		deviceID:  zk.deviceID,
 		maxChunk:  zk.maxChunk,
+
+		unknownCardCapture: zk.unknownCardCapture,
+		encoding:           zk.encoding,
 	}
 }
 
@@ -372,12 +570,17 @@ func (zk *ZK) UnlockTheDoor(delayInSeconds int) error {
 	return nil
 }
 
+// WriteLCD writes the text on the first line of the LCD
 func (zk *ZK) WriteLCD(text string) error {
-	if len(text) > 32 {
-		text = text[:32]
-	}
-	commandString := mustPack([]string{"H", "B"}, []interface{}{0, 0})
-	commandString = append(commandString, []byte(" "+text)...)
+	return zk.WriteLCDAt(0, 0, text)
+}
+
+// WriteLCDAt writes the text on the LCD at the given row and column.
+// The text is encoded with the charset of the device and cut to 32 bytes without splitting a character.
+func (zk *ZK) WriteLCDAt(row, col int, text string) error {
+	commandString := mustPack([]string{"H", "B"}, []interface{}{row, col})
+	commandString = append(commandString, ' ')
+	commandString = append(commandString, zk.encodeText(text, 32)...)
 	res, err := zk.sendCommand(CMD_WRITE_LCD, commandString, 8)
 	if err != nil {
 		return err
@@ -387,3 +590,33 @@ func (zk *ZK) WriteLCD(text string) error {
 	}
 	return nil
 }
+
+// ClearLCD clears the text written on the LCD, the device shows its default screen again
+func (zk *ZK) ClearLCD() error {
+	res, err := zk.sendCommand(CMD_CLEAR_LCD, nil, 8)
+	if err != nil {
+		return err
+	}
+	if !res.Status {
+		return errors.New("can not clear LCD")
+	}
+	return nil
+}
+
+// ShowMessage shows the lines on the LCD, one per row, for the given duration then restores the screen.
+// It blocks until the message is gone.
+func (zk *ZK) ShowMessage(lines []string, duration time.Duration) error {
+	if err := zk.ClearLCD(); err != nil {
+		return err
+	}
+
+	for row, line := range lines {
+		if err := zk.WriteLCDAt(row, 0, line); err != nil {
+			zk.ClearLCD()
+			return err
+		}
+	}
+
+	time.Sleep(duration)
+	return zk.ClearLCD()
+}
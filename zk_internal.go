@@ -1,12 +1,17 @@
 package gozk
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/unicode/norm"
 )
 
 type ZKProperties struct {
@@ -16,10 +21,16 @@ type ZKProperties struct {
 	Version      string
 	RecordCap    int
 	TotalRecords int
+	FreeRecords  int
 	UserCap      int
 	TotalUsers   int
+	FreeUsers    int
 	FingerCap    int
 	TotalFingers int
+	FreeFingers  int
+	TotalCards   int
+	FaceCap      int // Only set on devices with a face reader
+	TotalFaces   int // Only set on devices with a face reader
 }
 
 func (properties ZKProperties) Println() {
@@ -30,9 +41,17 @@ func (properties ZKProperties) Println() {
 	logrus.Println("Total Users:", properties.TotalUsers)
 	logrus.Println("Total Fingers:", properties.TotalFingers)
 	logrus.Println("Total Records:", properties.TotalRecords)
+	logrus.Println("Total Cards:", properties.TotalCards)
 	logrus.Println("Finger Capacity:", properties.FingerCap)
 	logrus.Println("User Capacity:", properties.UserCap)
 	logrus.Println("Record Capacity:", properties.RecordCap)
+	logrus.Println("Free Fingers:", properties.FreeFingers)
+	logrus.Println("Free Users:", properties.FreeUsers)
+	logrus.Println("Free Records:", properties.FreeRecords)
+	if properties.FaceCap > 0 {
+		logrus.Println("Total Faces:", properties.TotalFaces)
+		logrus.Println("Face Capacity:", properties.FaceCap)
+	}
 	if properties.TCP {
 		logrus.Println("Protocol: TCP")
 	} else {
@@ -48,8 +67,6 @@ func (zk *ZK) GetProperties() (*ZKProperties, error) {
 		return nil, err
 	}
 
-	fmt.Println("Firmware Version:", version)
-
 	clock, err := zk.GetTime()
 	if err != nil {
 		return nil, err
@@ -69,7 +86,9 @@ func (zk *ZK) GetProperties() (*ZKProperties, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &ZKProperties{
+
+		// The fields follow read_sizes of pyzk, fields 10 and 13 have no known meaning and are left out
+		properties := &ZKProperties{
 			ID:           zk.deviceID,
 			TCP:          zk.tcp,
 			Version:      version,
@@ -77,10 +96,25 @@ func (zk *ZK) GetProperties() (*ZKProperties, error) {
 			TotalUsers:   data[4].(int),
 			TotalFingers: data[6].(int),
 			TotalRecords: data[8].(int),
+			TotalCards:   data[12].(int),
 			FingerCap:    data[14].(int),
 			UserCap:      data[15].(int),
 			RecordCap:    data[16].(int),
-		}, nil
+			FreeFingers:  data[17].(int),
+			FreeUsers:    data[18].(int),
+			FreeRecords:  data[19].(int),
+		}
+
+		if len(res.Data) >= 92 {
+			faces, err := unpack([]string{"i", "i", "i"}, res.Data[80:92])
+			if err != nil {
+				return nil, err
+			}
+			properties.TotalFaces = faces[0].(int)
+			properties.FaceCap = faces[2].(int)
+		}
+
+		return properties, nil
 	} else if len(res.Data) >= 12 {
 		return nil, errors.New("failed to read data")
 	}
@@ -154,6 +188,51 @@ func (zk *ZK) readWithBuffer(command, fct, ext int) ([]byte, int, error) {
 	return data, start, nil
 }
 
+// sendWithBuffer uploads the buffer to the device with CMD_PREPARE_DATA followed by CMD_DATA chunks
+func (zk *ZK) sendWithBuffer(buffer []byte) error {
+	const chunkSize = 1024
+
+	if err := zk.freeData(); err != nil {
+		return err
+	}
+
+	commandString := mustPack([]string{"I"}, []interface{}{len(buffer)})
+	res, err := zk.sendCommand(CMD_PREPARE_DATA, commandString, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can not prepare data")
+	}
+
+	for start := 0; start < len(buffer); start += chunkSize {
+		end := start + chunkSize
+		if end > len(buffer) {
+			end = len(buffer)
+		}
+
+		if err := zk.sendChunk(buffer[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (zk *ZK) sendChunk(chunk []byte) error {
+	res, err := zk.sendCommand(CMD_DATA, chunk, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can not send chunk")
+	}
+
+	return nil
+}
+
 func (zk *ZK) freeData() error {
 	if _, err := zk.sendCommand(CMD_FREE_DATA, nil, 8); err != nil {
 		return err
@@ -402,6 +481,446 @@ func (zk *ZK) decodeTime(packet []byte) (time.Time, error) {
 	return time.Date(year, time.Month(month), day, hour, minute, second, 0, zk.loc), nil
 }
 
+// decodeUsers decodes the user records returned by CMD_USERTEMP_RRQ.
+// Old firmwares use a 28 bytes record, newer ones use a 72 bytes record.
+func (zk *ZK) decodeUsers(data []byte, packetSize int) ([]*User, error) {
+	users := []*User{}
+
+	if packetSize == 28 {
+		for len(data) >= 28 {
+			v, err := unpack([]string{"H", "B", "5s", "8s", "I", "B", "B", "h", "I"}, data[:28])
+			if err != nil {
+				return nil, err
+			}
+
+			users = append(users, &User{
+				UID:       v[0].(int),
+				Privilege: v[1].(int),
+				Password:  cString(v[2].(string)),
+				Name:      strings.TrimSpace(zk.decodeText(v[3].(string))),
+				Card:      uint32(v[4].(int)),
+				GroupID:   strconv.Itoa(v[6].(int)),
+				UserID:    strconv.Itoa(v[8].(int)),
+			})
+			data = data[28:]
+		}
+		return users, nil
+	}
+
+	for len(data) >= 72 {
+		v, err := unpack([]string{"H", "B", "8s", "24s", "I", "B", "7s", "B", "24s"}, data[:72])
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, &User{
+			UID:       v[0].(int),
+			Privilege: v[1].(int),
+			Password:  cString(v[2].(string)),
+			Name:      strings.TrimSpace(zk.decodeText(v[3].(string))),
+			Card:      uint32(v[4].(int)),
+			GroupID:   strings.TrimSpace(cString(v[6].(string))),
+			UserID:    cString(v[8].(string)),
+		})
+		data = data[72:]
+	}
+
+	return users, nil
+}
+
+// decodeAttendances decodes the attendance records returned by CMD_ATTLOG_RRQ.
+// Depending on the firmware a record is 8, 16 or 40 bytes long.
+func (zk *ZK) decodeAttendances(data []byte, recordSize int, users []*User) ([]*ScanEvent, error) {
+	attendances := []*ScanEvent{}
+
+	switch recordSize {
+	case 8:
+		for len(data) >= 8 {
+			v, err := unpack([]string{"H", "B", "4s", "B"}, data[:8])
+			if err != nil {
+				return nil, err
+			}
+
+			timestamp, err := zk.decodeTime([]byte(v[2].(string)))
+			if err != nil {
+				return nil, err
+			}
+
+			userID := strconv.Itoa(v[0].(int))
+			for _, user := range users {
+				if user.UID == v[0].(int) {
+					userID = user.UserID
+					break
+				}
+			}
+
+			attendances = append(attendances, &ScanEvent{
+				DeviceID:   zk.deviceID,
+				Timestamp:  timestamp,
+				UserID:     userID,
+				VerifyType: VerifyType(v[1].(int)),
+				PunchState: PunchState(v[3].(int)),
+			})
+			data = data[8:]
+		}
+	case 16:
+		for len(data) >= 16 {
+			v, err := unpack([]string{"I", "4s", "B", "B", "2s", "I"}, data[:16])
+			if err != nil {
+				return nil, err
+			}
+
+			timestamp, err := zk.decodeTime([]byte(v[1].(string)))
+			if err != nil {
+				return nil, err
+			}
+
+			attendances = append(attendances, &ScanEvent{
+				DeviceID:   zk.deviceID,
+				Timestamp:  timestamp,
+				UserID:     strconv.Itoa(v[0].(int)),
+				VerifyType: VerifyType(v[2].(int)),
+				PunchState: PunchState(v[3].(int)),
+				WorkCode:   v[5].(int),
+			})
+			data = data[16:]
+		}
+	case 40:
+		for len(data) >= 40 {
+			v, err := unpack([]string{"H", "24s", "B", "4s", "B", "I", "4s"}, data[:40])
+			if err != nil {
+				return nil, err
+			}
+
+			timestamp, err := zk.decodeTime([]byte(v[3].(string)))
+			if err != nil {
+				return nil, err
+			}
+
+			userID := cString(v[1].(string))
+			if userID == "" {
+				userID = strconv.Itoa(v[0].(int))
+			}
+			attendances = append(attendances, &ScanEvent{
+				DeviceID:   zk.deviceID,
+				Timestamp:  timestamp,
+				UserID:     userID,
+				VerifyType: VerifyType(v[2].(int)),
+				PunchState: PunchState(v[4].(int)),
+				WorkCode:   v[5].(int),
+			})
+			data = data[40:]
+		}
+	default:
+		return nil, fmt.Errorf("unknown attendance record size: %d bytes", recordSize)
+	}
+
+	return attendances, nil
+}
+
+// decodeLiveEvents decodes the attendance records pushed by a CMD_REG_EVENT packet.
+// Unknown layouts are skipped so that a bad payload never stops the capture.
+func (zk *ZK) decodeLiveEvents(data []byte) []*ScanEvent {
+	events := []*ScanEvent{}
+
+	for len(data) >= 12 {
+		var v []interface{}
+		var err error
+
+		switch {
+		case len(data) == 12:
+			v, err = unpack([]string{"I", "B", "B", "6s"}, data)
+			if err == nil {
+				v[0] = strconv.Itoa(v[0].(int))
+			}
+			data = data[12:]
+		case len(data) == 32:
+			v, err = unpack([]string{"24s", "B", "B", "6s"}, data[:32])
+			data = data[32:]
+		case len(data) == 36:
+			v, err = unpack([]string{"24s", "B", "B", "6s", "I"}, data[:36])
+			data = data[36:]
+		case len(data) >= 52:
+			v, err = unpack([]string{"24s", "B", "B", "6s", "I", "16s"}, data[:52])
+			data = data[52:]
+		default:
+			logrus.Warn("Unknown event layout:", hex.EncodeToString(data))
+			return events
+		}
+
+		if err != nil {
+			logrus.Warn("Failed to decode event:", err)
+			continue
+		}
+
+		event := &ScanEvent{
+			DeviceID:   zk.deviceID,
+			UserID:     cString(v[0].(string)),
+			Timestamp:  zk.decodeTimeHex([]byte(v[3].(string))),
+			VerifyType: VerifyType(v[1].(int)),
+			PunchState: PunchState(v[2].(int)),
+		}
+		if len(v) > 4 {
+			event.WorkCode = v[4].(int)
+		}
+		events = append(events, event)
+	}
+
+	return events
+}
+
+// decodeTemplates decodes the fingerprint templates returned by CMD_DB_RRQ.
+// Each template is prefixed by its size, the owner UID, the finger index and the valid flag.
+func (zk *ZK) decodeTemplates(data []byte) ([]*Finger, error) {
+	totalSize := mustUnpack([]string{"i"}, data[:4])[0].(int)
+	data = data[4:]
+
+	fingers := []*Finger{}
+	for totalSize > 0 && len(data) >= 6 {
+		v, err := unpack([]string{"H", "H", "b", "b"}, data[:6])
+		if err != nil {
+			return nil, err
+		}
+
+		size := v[0].(int)
+		if size < 6 || size > len(data) {
+			return nil, fmt.Errorf("invalid template size %d", size)
+		}
+
+		fingers = append(fingers, &Finger{
+			UID:      v[1].(int),
+			FingerID: v[2].(int),
+			Valid:    v[3].(int),
+			Template: append([]byte{}, data[6:size]...),
+		})
+		data = data[size:]
+		totalSize -= size
+	}
+
+	return fingers, nil
+}
+
+// decodeOperationLog decodes the 16 bytes operation records returned by CMD_OPLOG_RRQ
+func (zk *ZK) decodeOperationLog(data []byte) ([]*OperationLog, error) {
+	logs := []*OperationLog{}
+
+	for len(data) >= 16 {
+		v, err := unpack([]string{"H", "B", "B", "4s", "H", "H", "H", "H"}, data[:16])
+		if err != nil {
+			return nil, err
+		}
+
+		timestamp, err := zk.decodeTime([]byte(v[3].(string)))
+		if err != nil {
+			return nil, err
+		}
+
+		logs = append(logs, &OperationLog{
+			DeviceID:  zk.deviceID,
+			AdminID:   v[0].(int),
+			Operation: OperationCode(v[1].(int)),
+			Timestamp: timestamp,
+			Params:    [4]int{v[4].(int), v[5].(int), v[6].(int), v[7].(int)},
+		})
+		data = data[16:]
+	}
+
+	return logs, nil
+}
+
+// decodeUnknownCard decodes the card number pushed by an EF_HIDNUM event.
+// It returns nil if the card is assigned to a user, the swipe is then reported as an attendance.
+func (zk *ZK) decodeUnknownCard(data []byte, knownCards map[uint32]bool) *ScanEvent {
+	if len(data) < 4 {
+		return nil
+	}
+
+	card := uint32(mustUnpack([]string{"I"}, data[:4])[0].(int))
+	if card == 0 || knownCards[card] {
+		return nil
+	}
+
+	return &ScanEvent{DeviceID: zk.deviceID, Card: card, Timestamp: time.Now().In(zk.loc)}
+}
+
+// encodeText encodes the text with the charset of the device, cut to at most size bytes
+// without splitting a character.
+func (zk *ZK) encodeText(text string, size int) []byte {
+	if zk.encoding == nil {
+		return []byte(truncateText(text, size))
+	}
+
+	encoder := zk.encoding.NewEncoder()
+	data := []byte{}
+	for _, r := range text {
+		encoded := encodeRune(encoder, r)
+		if len(data)+len(encoded) > size {
+			break
+		}
+		data = append(data, encoded...)
+	}
+
+	return data
+}
+
+// encodeRune encodes a single character. Characters missing from the charset are decomposed
+// into a base letter followed by combining marks, which is how CP1258 writes Vietnamese tones.
+func encodeRune(encoder *encoding.Encoder, r rune) []byte {
+	if encoded, err := encoder.Bytes([]byte(string(r))); err == nil {
+		return encoded
+	}
+
+	decomposed := []rune(norm.NFD.String(string(r)))
+	for k := len(decomposed); k > 0; k-- {
+		base, err := encoder.Bytes([]byte(norm.NFC.String(string(decomposed[:k]))))
+		if err != nil {
+			continue
+		}
+
+		marks, err := encoder.Bytes([]byte(string(decomposed[k:])))
+		if err != nil {
+			continue
+		}
+
+		return append(base, marks...)
+	}
+
+	return []byte{'?'}
+}
+
+// decodeText decodes a NUL terminated text sent by the device with its charset
+func (zk *ZK) decodeText(text string) string {
+	text = cString(text)
+	if zk.encoding == nil {
+		return text
+	}
+
+	decoded, err := zk.encoding.NewDecoder().String(text)
+	if err != nil {
+		return text
+	}
+
+	return norm.NFC.String(decoded)
+}
+
+// encodeUser encodes the user into the record layout used by the connected device
+func (zk *ZK) encodeUser(user *User, packetSize int) ([]byte, error) {
+	if packetSize != 28 && packetSize != 72 {
+		return nil, fmt.Errorf("unsupported user packet size %d", packetSize)
+	}
+
+	if packetSize == 28 {
+		userID, err := strconv.Atoi(user.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("user_id %q must be numeric on this device", user.UserID)
+		}
+
+		groupID := 0
+		if user.GroupID != "" {
+			if groupID, err = strconv.Atoi(user.GroupID); err != nil {
+				return nil, fmt.Errorf("group_id %q must be numeric on this device", user.GroupID)
+			}
+		}
+
+		return newBP().Pack([]string{"H", "B", "5s", "8s", "I", "B", "B", "h", "I"}, []interface{}{
+			user.UID,
+			user.Privilege,
+			truncate(user.Password, 5),
+			string(zk.encodeText(user.Name, 8)),
+			int(user.Card),
+			0,
+			groupID,
+			0,
+			userID,
+		})
+	}
+
+	return newBP().Pack([]string{"H", "B", "8s", "24s", "I", "B", "7s", "B", "24s"}, []interface{}{
+		user.UID,
+		user.Privilege,
+		truncate(user.Password, 8),
+		string(zk.encodeText(user.Name, 24)),
+		int(user.Card),
+		0,
+		truncate(user.GroupID, 7),
+		0,
+		truncate(user.UserID, 24),
+	})
+}
+
+// detectUserPacketSize reads the user table to learn which user record layout the device uses.
+// The layout is only remembered once the table has users, an empty table falls back to the transport default.
+func (zk *ZK) detectUserPacketSize() (int, error) {
+	if zk.userPacketSize == 0 {
+		if _, err := zk.GetUsers(); err != nil {
+			return 0, err
+		}
+	}
+
+	if zk.userPacketSize != 0 {
+		return zk.userPacketSize, nil
+	}
+
+	return userPacketSize(0, 0, zk.tcp)
+}
+
+// userPacketSize returns the size of a user record from the size of the user table.
+// An empty table doesn't tell the layout, like pyzk TCP devices are assumed to use 72 bytes and UDP ones 28 bytes.
+func userPacketSize(totalSize, totalUsers int, tcp bool) (int, error) {
+	if totalUsers == 0 {
+		if tcp {
+			return 72, nil
+		}
+		return 28, nil
+	}
+
+	if size := totalSize / totalUsers; size == 28 || size == 72 {
+		return size, nil
+	}
+
+	return 0, fmt.Errorf("unknown user record size: %d bytes for %d users", totalSize, totalUsers)
+}
+
+func (zk *ZK) refreshData() error {
+	res, err := zk.sendCommand(CMD_REFRESHDATA, nil, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can not refresh data")
+	}
+
+	return nil
+}
+
+// shutdown sends a restart or power off command, the device drops the connection right after it
+func (zk *ZK) shutdown(command int) error {
+	res, err := zk.sendCommand(command, nil, 8)
+	zk.resetConnection()
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return fmt.Errorf("can not send command %d", command)
+	}
+
+	return nil
+}
+
+// resetConnection closes the socket and forgets the session so that Connect can be called again
+func (zk *ZK) resetConnection() {
+	if zk.conn != nil {
+		zk.conn.Close()
+	}
+
+	zk.conn = nil
+	zk.sessionID = 0
+	zk.replyID = USHRT_MAX - 1
+	zk.disabled = false
+}
+
 func (zk *ZK) verifyUser() error {
 	res, err := zk.sendCommand(CMD_STARTVERIFY, nil, 8)
 	if err != nil {
@@ -415,6 +934,19 @@ func (zk *ZK) verifyUser() error {
 	return nil
 }
 
+func (zk *ZK) cancelCapture() error {
+	res, err := zk.sendCommand(CMD_CANCELCAPTURE, nil, 8)
+	if err != nil {
+		return err
+	}
+
+	if !res.Status {
+		return errors.New("can't cancel capture")
+	}
+
+	return nil
+}
+
 func (zk *ZK) regEvent(flag int) error {
 
 	commandString, err := newBP().Pack([]string{"I"}, []interface{}{flag})
@@ -448,6 +980,56 @@ func (zk *ZK) receiveData(size int, timeout time.Duration) ([]byte, error) {
 	return data[:n], nil
 }
 
+// receiveEvent waits for an event pushed by the device and acknowledges it.
+// It returns the event flag carried in the session field of the header and the event payload.
+func (zk *ZK) receiveEvent(timeout time.Duration) (int, []byte, error) {
+	code, flag, data, err := zk.receivePacket(timeout)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := zk.ackOK(); err != nil {
+		return 0, nil, err
+	}
+
+	if code != CMD_REG_EVENT {
+		return 0, nil, nil
+	}
+
+	return flag, data, nil
+}
+
+// receivePacket waits for a packet sent by the device without a prior command.
+// It returns the response code, the session field of the header and the payload.
+func (zk *ZK) receivePacket(timeout time.Duration) (int, int, []byte, error) {
+	data, err := zk.receiveData(1032, timeout)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	return decodePacket(data, zk.tcp)
+}
+
+// decodePacket splits a received packet into its command code, session field and payload
+func decodePacket(data []byte, tcp bool) (int, int, []byte, error) {
+	var header []interface{}
+	if tcp {
+		if len(data) < 16 {
+			return 0, 0, nil, errors.New("TCP packet invalid")
+		}
+		header = mustUnpack([]string{"H", "H", "H", "H"}, data[8:16])
+		data = data[16:]
+	} else {
+		if len(data) < 8 {
+			return 0, 0, nil, errors.New("UDP packet invalid")
+		}
+		header = mustUnpack([]string{"H", "H", "H", "H"}, data[:8])
+		data = data[8:]
+	}
+
+	return header[0].(int), header[2].(int), data, nil
+}
+
 func (zk *ZK) ackOK() error {
 
 	buf, err := createHeader(CMD_ACK_OK, nil, zk.sessionID, USHRT_MAX-1)
@@ -492,6 +1074,10 @@ func (zk *ZK) sendCommand(command int, commandString []byte, responseSize int) (
 		return nil, errors.New("cannot send command when capturing")
 	}
 
+	if zk.enrolling {
+		return nil, errors.New("cannot send command when enrolling")
+	}
+
 	if commandString == nil {
 		commandString = make([]byte, 0)
 	}
@@ -1,6 +1,9 @@
 package gozk
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
@@ -22,6 +25,28 @@ func TestSocketConnect(t *testing.T) {
 	require.NoError(t, socket.Disconnect())
 }
 
+func TestSocketGetProperties(t *testing.T) {
+	socket := NewZK(testZkHost, WithTimezone(testTimezone), WithTCP(true))
+	require.NoError(t, socket.Connect())
+	defer socket.Disconnect()
+
+	properties, err := socket.GetProperties()
+	require.NoError(t, err)
+	require.Equal(t, properties.UserCap, properties.TotalUsers+properties.FreeUsers)
+	require.Equal(t, properties.RecordCap, properties.TotalRecords+properties.FreeRecords)
+}
+
+func TestSocketRestart(t *testing.T) {
+	socket := NewZK(testZkHost, WithTimezone(testTimezone), WithTCP(true))
+	require.NoError(t, socket.Connect())
+	require.NoError(t, socket.Restart())
+
+	require.Eventually(t, func() bool {
+		return socket.Connect() == nil
+	}, 2*time.Minute, 5*time.Second)
+	require.NoError(t, socket.Disconnect())
+}
+
 func TestSocketGetAttendances(t *testing.T) {
 	socket := NewZK(testZkHost, WithTimezone(testTimezone), WithTCP(true))
 	require.NoError(t, socket.Connect())
@@ -39,11 +64,515 @@ func TestSocketGetAttendances(t *testing.T) {
 	time.Sleep(time.Second * 1)
 }
 
+func TestSocketClearAttendanceAfterExport(t *testing.T) {
+	socket := NewZK(testZkHost, WithTimezone(testTimezone), WithTCP(true))
+	require.NoError(t, socket.Connect())
+	defer socket.Disconnect()
+
+	exportErr := errors.New("export failed")
+	require.Equal(t, exportErr, socket.ClearAttendanceAfterExport(func(events []*ScanEvent) error {
+		return exportErr
+	}))
+
+	properties, err := socket.GetProperties()
+	require.NoError(t, err)
+	require.NotZero(t, properties.TotalRecords)
+}
+
 func TestSocketGetUsers(t *testing.T) {
 	socket := NewZK(testZkHost, WithTimezone(testTimezone), WithTCP(true))
 	require.NoError(t, socket.Connect())
 	defer socket.Disconnect()
-	require.NoError(t, socket.GetUsers())
+
+	properties, err := socket.GetProperties()
+	require.NoError(t, err)
+
+	users, err := socket.GetUsers()
+	require.NoError(t, err)
+	require.Equal(t, properties.TotalUsers, len(users))
+}
+
+func TestDecodeUsers(t *testing.T) {
+	zk := NewZK(testZkHost)
+
+	data := mustPack([]string{"H", "B", "5s", "8s", "I", "B", "B", "h", "I"}, []interface{}{1, USER_ADMIN, "123", "Linh", 4242, 0, 1, 0, 41})
+	users, err := zk.decodeUsers(data, 28)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, User{UID: 1, UserID: "41", Name: "Linh", Privilege: USER_ADMIN, Password: "123", GroupID: "1", Card: 4242}, *users[0])
+
+	data = mustPack([]string{"H", "B", "8s", "24s", "I", "B", "7s", "B", "24s"}, []interface{}{2, USER_DEFAULT, "", "Nguyen Van A", 0, 0, "1", 0, "EMP-0042"})
+	users, err = zk.decodeUsers(data, 72)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, User{UID: 2, UserID: "EMP-0042", Name: "Nguyen Van A", Privilege: USER_DEFAULT, GroupID: "1"}, *users[0])
+}
+
+func TestEncodeUsers(t *testing.T) {
+	zk := NewZK(testZkHost)
+	user := &User{UID: 3, UserID: "42", Name: "Tran Thi B", Privilege: USER_DEFAULT, Password: "1", GroupID: "1", Card: 99}
+
+	for _, packetSize := range []int{28, 72} {
+		data, err := zk.encodeUser(user, packetSize)
+		require.NoError(t, err)
+		require.Len(t, data, packetSize)
+
+		users, err := zk.decodeUsers(data, packetSize)
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		require.Equal(t, user.UserID, users[0].UserID)
+		require.Equal(t, user.Card, users[0].Card)
+	}
+
+	_, err := zk.encodeUser(&User{UID: 4, UserID: "EMP-0042"}, 28)
+	require.Error(t, err)
+
+	_, err = zk.encodeUser(user, 0)
+	require.Error(t, err)
+}
+
+func TestUserPacketSize(t *testing.T) {
+	packetSize, err := userPacketSize(0, 0, false)
+	require.NoError(t, err)
+	require.Equal(t, 28, packetSize)
+
+	packetSize, err = userPacketSize(720, 10, false)
+	require.NoError(t, err)
+	require.Equal(t, 72, packetSize)
+
+	packetSize, err = userPacketSize(280, 10, true)
+	require.NoError(t, err)
+	require.Equal(t, 28, packetSize)
+
+	_, err = userPacketSize(400, 10, true)
+	require.Error(t, err)
+
+	zk := NewZK(testZkHost, WithTCP(false))
+	packetSize, _ = userPacketSize(0, 0, zk.tcp)
+	data, err := zk.encodeUser(&User{UID: 1, UserID: "1", Name: "first user"}, packetSize)
+	require.NoError(t, err)
+	require.Len(t, data, 28)
+
+	// An empty TCP device takes the first user in the 72 bytes layout, non numeric user IDs included
+	zk = NewZK(testZkHost, WithTCP(true))
+	packetSize, err = userPacketSize(0, 0, zk.tcp)
+	require.NoError(t, err)
+	data, err = zk.encodeUser(&User{UID: 1, UserID: "EMP-0001", Name: "first user"}, packetSize)
+	require.NoError(t, err)
+	require.Len(t, data, 72)
+}
+
+func TestDecodeAttendances(t *testing.T) {
+	zk := NewZK(testZkHost, WithTimezone(testTimezone))
+	at := time.Date(2024, time.March, 8, 8, 30, 0, 0, zk.loc)
+	users := []*User{{UID: 7, UserID: "41"}}
+
+	data := mustPack([]string{"H", "B", "I", "B"}, []interface{}{7, 1, zk.encodeTime(at), 0})
+	events, err := zk.decodeAttendances(data, 8, users)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, "41", events[0].UserID)
+	require.True(t, at.Equal(events[0].Timestamp))
+
+	data = mustPack([]string{"I", "I", "B", "B", "2s", "I"}, []interface{}{41, zk.encodeTime(at), 1, 1, "", 5})
+	events, err = zk.decodeAttendances(data, 16, users)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, "41", events[0].UserID)
+	require.True(t, at.Equal(events[0].Timestamp))
+	require.Equal(t, VerifyFingerprint, events[0].VerifyType)
+	require.Equal(t, PunchCheckOut, events[0].PunchState)
+	require.Equal(t, 5, events[0].WorkCode)
+
+	data = mustPack([]string{"H", "24s", "B", "I", "B", "I", "4s"}, []interface{}{7, "41", 15, zk.encodeTime(at), 4, 0, ""})
+	events, err = zk.decodeAttendances(data, 40, users)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, "41", events[0].UserID)
+	require.Equal(t, VerifyFace, events[0].VerifyType)
+	require.Equal(t, PunchOTIn, events[0].PunchState)
+
+	_, err = zk.decodeAttendances(make([]byte, 36), 36, users)
+	require.Error(t, err)
+}
+
+func TestDecodeLiveEvents(t *testing.T) {
+	zk := NewZK(testZkHost, WithTimezone(testTimezone))
+
+	data := mustPack([]string{"24s", "B", "B", "B", "B", "B", "B", "B", "B", "I"}, []interface{}{"EMP-0042", 1, 0, 24, 3, 8, 8, 30, 0, 3})
+	events := zk.decodeLiveEvents(data)
+	require.Len(t, events, 1)
+	require.Equal(t, "EMP-0042", events[0].UserID)
+	require.Equal(t, 3, events[0].WorkCode)
+	require.True(t, time.Date(2024, time.March, 8, 8, 30, 0, 0, zk.loc).Equal(events[0].Timestamp))
+
+	_, err := events[0].NumericUserID()
+	require.Error(t, err)
+
+	require.Empty(t, zk.decodeLiveEvents(make([]byte, 20)))
+}
+
+func TestDecodePacket(t *testing.T) {
+	header := mustPack([]string{"H", "H", "H", "H"}, []interface{}{CMD_REG_EVENT, 0, EF_HIDNUM, 0})
+
+	code, flag, data, err := decodePacket(append(header, 1, 2), false)
+	require.NoError(t, err)
+	require.Equal(t, CMD_REG_EVENT, code)
+	require.Equal(t, EF_HIDNUM, flag)
+	require.Equal(t, []byte{1, 2}, data)
+
+	_, _, _, err = decodePacket(header[:5], false)
+	require.Error(t, err)
+
+	_, _, _, err = decodePacket(append(make([]byte, 4), header...)[:12], true)
+	require.Error(t, err)
+}
+
+func TestDecodeTemplates(t *testing.T) {
+	zk := NewZK(testZkHost)
+
+	data := mustPack([]string{"i", "H", "H", "b", "b", "4s", "H", "H", "b", "b", "2s"}, []interface{}{22, 10, 1, 6, 1, "abcd", 8, 2, 0, 1, "ef"})
+	fingers, err := zk.decodeTemplates(data)
+	require.NoError(t, err)
+	require.Len(t, fingers, 2)
+	require.Equal(t, Finger{UID: 1, FingerID: 6, Valid: 1, Template: []byte("abcd")}, *fingers[0])
+	require.Equal(t, Finger{UID: 2, FingerID: 0, Valid: 1, Template: []byte("ef")}, *fingers[1])
+}
+
+func TestDecodeOperationLog(t *testing.T) {
+	zk := NewZK(testZkHost, WithTimezone(testTimezone))
+	at := time.Date(2024, time.March, 8, 8, 30, 0, 0, zk.loc)
+
+	data := mustPack([]string{"H", "B", "B", "I", "H", "H", "H", "H"}, []interface{}{1, int(OpDeleteUser), 0, zk.encodeTime(at), 42, 0, 0, 0})
+	logs, err := zk.decodeOperationLog(data)
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, 1, logs[0].AdminID)
+	require.Equal(t, OpDeleteUser, logs[0].Operation)
+	require.Equal(t, [4]int{42, 0, 0, 0}, logs[0].Params)
+	require.True(t, at.Equal(logs[0].Timestamp))
+}
+
+func TestParseOption(t *testing.T) {
+	key, value := parseOption([]byte("~SerialNumber=A8N5230560263\x00\x00"))
+	require.Equal(t, "~SerialNumber", key)
+	require.Equal(t, "A8N5230560263", value)
+
+	key, value = parseOption([]byte("IPAddress=192.168.1.201=x\x00"))
+	require.Equal(t, "IPAddress", key)
+	require.Equal(t, "192.168.1.201=x", value)
+}
+
+func TestEncodeTimeZone(t *testing.T) {
+	tz := &AccessTimeZone{ID: 2, Windows: map[time.Weekday]TimeWindow{
+		time.Monday: {StartHour: 8, EndHour: 17, EndMinute: 30},
+		time.Friday: {StartHour: 8, EndHour: 12},
+	}}
+
+	data := encodeTimeZone(tz)
+	require.Len(t, data, 28)
+
+	decoded := decodeTimeZone(2, data)
+	require.Len(t, decoded.Windows, 7)
+	require.Equal(t, tz.Windows[time.Monday], decoded.Windows[time.Monday])
+	require.Equal(t, tz.Windows[time.Friday], decoded.Windows[time.Friday])
+	require.Equal(t, TimeWindow{}, decoded.Windows[time.Sunday])
+	require.True(t, decoded.Windows[time.Monday].Contains(time.Date(2024, time.March, 4, 17, 30, 0, 0, time.UTC)))
+	require.False(t, decoded.Windows[time.Monday].Contains(time.Date(2024, time.March, 4, 17, 31, 0, 0, time.UTC)))
+	require.False(t, decoded.Windows[time.Sunday].Contains(time.Date(2024, time.March, 3, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestEncodeUnlockCombinations(t *testing.T) {
+	combinations := []*UnlockCombination{{ID: 1, Groups: []int{1, 2}}, {ID: 3, Groups: []int{2, 2, 2}}}
+
+	data, err := encodeUnlockCombinations(combinations)
+	require.NoError(t, err)
+	require.Equal(t, combinations, decodeUnlockCombinations(data))
+
+	_, err = encodeUnlockCombinations([]*UnlockCombination{{ID: 11, Groups: []int{1}}})
+	require.Error(t, err)
+}
+
+func TestEncodeMessage(t *testing.T) {
+	zk := NewZK(testZkHost, WithTimezone(testTimezone))
+	message := &Message{
+		ID:           1,
+		Tag:          MessagePersonal,
+		StartTime:    time.Date(2024, time.March, 8, 8, 0, 0, 0, zk.loc),
+		ValidMinutes: 60,
+		Content:      "Your timesheet is overdue",
+	}
+
+	data, err := zk.encodeMessage(message)
+	require.NoError(t, err)
+	require.Len(t, data, messageRecordSize)
+
+	messages, err := zk.decodeMessages(data)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.Equal(t, message.Content, messages[0].Content)
+	require.Equal(t, message.Tag, messages[0].Tag)
+	require.True(t, message.StartTime.Equal(messages[0].StartTime))
+}
+
+func TestDecodeUnknownCard(t *testing.T) {
+	zk := NewZK(testZkHost, WithTimezone(testTimezone), WithUnknownCardCapture(true))
+	require.True(t, zk.unknownCardCapture)
+
+	knownCards := map[uint32]bool{4242: true}
+	require.Nil(t, zk.decodeUnknownCard(mustPack([]string{"I"}, []interface{}{4242}), knownCards))
+
+	event := zk.decodeUnknownCard(mustPack([]string{"I"}, []interface{}{1234}), knownCards)
+	require.NotNil(t, event)
+	require.Equal(t, uint32(1234), event.Card)
+	require.Empty(t, event.UserID)
+}
+
+func TestFindUserByCardZero(t *testing.T) {
+	zk := NewZK(testZkHost)
+	_, err := zk.FindUserByCard(0)
+	require.Error(t, err)
+}
+
+func TestTruncateText(t *testing.T) {
+	require.Equal(t, "Hello", truncateText("Hello", 32))
+	require.Equal(t, "Xin ch", truncateText("Xin chào", 7))
+	require.Equal(t, "Xin chà", truncateText("Xin chào", 8))
+}
+
+func TestEncodeText(t *testing.T) {
+	for _, charset := range []string{"utf-8", "windows-1258", "gbk"} {
+		zk := NewZK(testZkHost, WithEncoding(charset))
+		name := "Nguyễn Thị Ánh"
+		if charset == "gbk" {
+			name = "王小明"
+		}
+
+		data, err := zk.encodeUser(&User{UID: 1, UserID: "1", Name: name}, 72)
+		require.NoError(t, err)
+
+		users, err := zk.decodeUsers(data, 72)
+		require.NoError(t, err)
+		require.Equal(t, name, users[0].Name, charset)
+	}
+
+	zk := NewZK(testZkHost, WithEncoding("windows-1258"))
+	require.Len(t, zk.encodeText("ễ", 2), 2)
+	require.Empty(t, zk.encodeText("ễ", 1))
+
+	require.NotPanics(t, func() { zk = NewZK(testZkHost, WithEncoding("tcvn")) })
+	require.Nil(t, zk.encoding)
+	require.Equal(t, "ễ", zk.decodeText(string(zk.encodeText("ễ", 3))))
+}
+
+func TestEncodeUsersWithTemplates(t *testing.T) {
+	zk := NewZK(testZkHost)
+	template := make([]byte, 1500)
+	user := &User{UID: 5, UserID: "5", Name: "gozk"}
+
+	buffer, err := zk.encodeUsersWithTemplates([]*UserTemplates{{User: user, Fingers: []*Finger{{FingerID: 6, Valid: 1, Template: template}}}}, 72)
+	require.NoError(t, err)
+
+	sizes := mustUnpack([]string{"I", "I", "I"}, buffer[:12])
+	require.Equal(t, []interface{}{73, 8, 2 + len(template)}, sizes)
+	require.Len(t, buffer, 12+73+8+2+len(template))
+
+	table := mustUnpack([]string{"b", "H", "b", "I"}, buffer[12+73:12+73+8])
+	require.Equal(t, []interface{}{2, 5, 0x16, 0}, table)
+	require.Equal(t, template, buffer[12+73+8+2:])
+}
+
+func TestDecodeEnrollment(t *testing.T) {
+	result := func(code int) []byte {
+		return mustPack([]string{"H", "H"}, []interface{}{code, 0})
+	}
+
+	statuses := func(state *enrollment, events [][2]interface{}) []EnrollStatus {
+		got := []EnrollStatus{}
+		for _, e := range events {
+			data, _ := e[1].([]byte)
+			if event := state.decode(e[0].(int), data); event != nil {
+				got = append(got, event.Status)
+			}
+		}
+		return got
+	}
+
+	state := &enrollment{uid: 1, fingerID: 6}
+	require.Equal(t, []EnrollStatus{
+		EnrollFingerPressed, EnrollPressAccepted,
+		EnrollFingerPressed, EnrollPressAccepted,
+		EnrollFingerPressed, EnrollPressAccepted,
+		EnrollSuccess,
+	}, statuses(state, [][2]interface{}{
+		{EF_FINGER, nil}, {EF_ENROLLFINGER, result(0x64)},
+		{EF_FINGER, nil}, {EF_ENROLLFINGER, result(0x64)},
+		{EF_FINGER, nil}, {EF_ENROLLFINGER, result(0x64)},
+		{EF_ENROLLFINGER, result(0)},
+	}))
+	require.Equal(t, 3, state.presses)
+
+	state = &enrollment{uid: 1, fingerID: 6}
+	require.Equal(t, []EnrollStatus{EnrollFingerPressed, EnrollDuplicate}, statuses(state, [][2]interface{}{
+		{EF_FINGER, nil}, {EF_ENROLLFINGER, result(5)},
+	}))
+
+	state = &enrollment{uid: 1, fingerID: 6}
+	require.Equal(t, []EnrollStatus{EnrollFingerPressed, EnrollPressAccepted, EnrollTimeout}, statuses(state, [][2]interface{}{
+		{EF_FINGER, nil}, {EF_ENROLLFINGER, result(0x64)}, {EF_ENROLLFINGER, result(6)},
+	}))
+
+	state = &enrollment{uid: 1, fingerID: 6}
+	event := state.decode(EF_ENROLLFINGER, result(4))
+	require.Equal(t, EnrollFailed, event.Status)
+	require.Error(t, event.Error)
+	require.Nil(t, state.decode(EF_ATTLOG, nil))
+}
+
+func TestSocketSetAndDeleteUser(t *testing.T) {
+	socket := NewZK(testZkHost, WithTimezone(testTimezone), WithTCP(true))
+	require.NoError(t, socket.Connect())
+	defer socket.Disconnect()
+
+	require.NoError(t, socket.SetUser(&User{UID: 999, UserID: "999", Name: "gozk", Privilege: USER_DEFAULT}))
+	require.NoError(t, socket.DeleteUser(999))
+}
+
+func TestSocketGetTemplates(t *testing.T) {
+	socket := NewZK(testZkHost, WithTimezone(testTimezone), WithTCP(true))
+	require.NoError(t, socket.Connect())
+	defer socket.Disconnect()
+
+	properties, err := socket.GetProperties()
+	require.NoError(t, err)
+
+	fingers, err := socket.GetTemplates()
+	require.NoError(t, err)
+	require.Equal(t, properties.TotalFingers, len(fingers))
+}
+
+func TestSocketSaveUserTemplate(t *testing.T) {
+	socket := NewZK(testZkHost, WithTimezone(testTimezone), WithTCP(true))
+	require.NoError(t, socket.Connect())
+	defer socket.Disconnect()
+
+	templates, err := socket.GetTemplates()
+	require.NoError(t, err)
+	if len(templates) == 0 {
+		t.Skip("no template to copy")
+	}
+
+	finger := *templates[0]
+	user := &User{UID: 999, UserID: "999", Name: "gozk", Privilege: USER_DEFAULT}
+	require.NoError(t, socket.SaveUserTemplate(user, &finger))
+
+	fingers, err := socket.GetUserTemplates(999)
+	require.NoError(t, err)
+	require.Len(t, fingers, 1)
+	require.Equal(t, finger.Template, fingers[0].Template)
+
+	exists, err := socket.HasTemplate(999, finger.FingerID)
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	require.NoError(t, socket.DeleteUserTemplate(999, finger.FingerID))
+	exists, err = socket.HasTemplate(999, finger.FingerID)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	require.NoError(t, socket.DeleteUser(999))
+}
+
+func TestSocketCancelEnrollUser(t *testing.T) {
+	socket := NewZK(testZkHost, WithTimezone(testTimezone), WithTCP(true))
+	require.NoError(t, socket.Connect())
+	defer socket.Disconnect()
+
+	require.NoError(t, socket.SetUser(&User{UID: 999, UserID: "999", Name: "gozk", Privilege: USER_DEFAULT}))
+	defer socket.DeleteUser(999)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := socket.EnrollUser(ctx, 999, 0)
+	require.NoError(t, err)
+	cancel()
+
+	var last *EnrollEvent
+	for event := range events {
+		last = event
+	}
+	require.NotNil(t, last)
+	require.Equal(t, EnrollCancelled, last.Status)
+}
+
+func TestSocketGetSerialNumber(t *testing.T) {
+	socket := NewZK(testZkHost, WithTimezone(testTimezone), WithTCP(true))
+	require.NoError(t, socket.Connect())
+	defer socket.Disconnect()
+
+	serialNumber, err := socket.GetSerialNumber()
+	require.NoError(t, err)
+	require.NotEmpty(t, serialNumber)
+}
+
+func TestSocketGetDoorState(t *testing.T) {
+	socket := NewZK(testZkHost, WithTimezone(testTimezone), WithTCP(true))
+	require.NoError(t, socket.Connect())
+	defer socket.Disconnect()
+
+	state, err := socket.GetDeviceState()
+	require.NoError(t, err)
+	require.Equal(t, DeviceIdle, state.State)
+
+	_, err = socket.GetDoorState()
+	require.NoError(t, err)
+}
+
+func TestSocketAccessControlSnapshot(t *testing.T) {
+	socket := NewZK(testZkHost, WithTimezone(testTimezone), WithTCP(true))
+	require.NoError(t, socket.Connect())
+	defer socket.Disconnect()
+
+	snapshot, err := socket.AccessControlSnapshot()
+	require.NoError(t, err)
+	require.Len(t, snapshot.TimeZones, maxTimeZones)
+	require.Len(t, snapshot.GroupTimeZones, maxGroups)
+
+	data, err := json.Marshal(snapshot)
+	require.NoError(t, err)
+
+	restored := &AccessControl{}
+	require.NoError(t, json.Unmarshal(data, restored))
+	require.Equal(t, snapshot, restored)
+
+	require.NoError(t, socket.ApplyAccessControl(restored))
+	applied, err := socket.AccessControlSnapshot()
+	require.NoError(t, err)
+	require.Equal(t, snapshot, applied)
+}
+
+func TestSocketEmptyMifareCardTimeout(t *testing.T) {
+	socket := NewZK(testZkHost, WithTimezone(testTimezone), WithTCP(true))
+	require.NoError(t, socket.Connect())
+	defer socket.Disconnect()
+
+	defer func(timeout time.Duration) { CardTimeout = timeout }(CardTimeout)
+	CardTimeout = 3 * time.Second
+
+	require.Equal(t, ErrCardTimeout, socket.EmptyMifareCard())
+}
+
+func TestSocketAssignCard(t *testing.T) {
+	socket := NewZK(testZkHost, WithTimezone(testTimezone), WithTCP(true))
+	require.NoError(t, socket.Connect())
+	defer socket.Disconnect()
+
+	require.NoError(t, socket.SetUser(&User{UID: 999, UserID: "999", Name: "gozk", Privilege: USER_DEFAULT}))
+	defer socket.DeleteUser(999)
+
+	require.NoError(t, socket.AssignCard(999, 12345678))
+	user, err := socket.FindUserByCard(12345678)
+	require.NoError(t, err)
+	require.Equal(t, 999, user.UID)
 }
 
 func TestUnlockTheDoor(t *testing.T) {
@@ -60,4 +589,7 @@ func TestWriteLCD(t *testing.T) {
 	defer socket.Disconnect()
 
 	require.NoError(t, socket.WriteLCD("Hello world"))
+	require.NoError(t, socket.WriteLCDAt(1, 2, "Shift starts at 8:00"))
+	require.NoError(t, socket.ClearLCD())
+	require.NoError(t, socket.ShowMessage([]string{"Hello", "world"}, time.Second))
 }